@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errorCodeUnauthorized = "unauthorized"
+	errorCodeForbidden    = "forbidden"
+
+	anonymousUser = "_anonymous_"
+)
+
+var (
+	authMethods   string
+	authApiKeys   string
+	authJwtSecret string
+	adminUsers    string
+
+	authenticators []authenticator
+	admins         map[string]bool
+
+	errMissingCredentials = errors.New("missing credentials")
+	errInvalidApiKey      = errors.New("invalid api key")
+	errInvalidToken       = errors.New("invalid token")
+	errExpiredToken       = errors.New("expired token")
+)
+
+type userKey struct{}
+
+// Derives the identity of the caller from the credentials within the
+// request headers. The returned flag tells whether the request carries
+// credentials for this authenticator at all.
+type authenticator interface {
+	name() string
+	authenticate(header http.Header) (string, bool, error)
+}
+
+func createAuthenticators() {
+	admins = map[string]bool{}
+	for _, user := range strings.Split(adminUsers, ",") {
+		user = strings.TrimSpace(user)
+		if user != "" && user != anonymousUser {
+			admins[user] = true
+		}
+	}
+
+	authenticators = []authenticator{}
+	for _, method := range strings.Split(authMethods, ",") {
+		switch strings.TrimSpace(method) {
+		case "apikey":
+			authenticators = append(authenticators, newApiKeyAuthenticator(authApiKeys))
+		case "jwt":
+			// Tokens signed with an empty secret could be forged by anyone
+			if authJwtSecret == "" {
+				panic("authentication method jwt requires a secret")
+			}
+			authenticators = append(authenticators, newJwtAuthenticator([]byte(authJwtSecret)))
+		case "":
+		default:
+			panic("unknown authentication method: " + method)
+		}
+	}
+}
+
+// Static API keys which are mapped to users.
+type apiKeyAuthenticator struct {
+	keys map[string]string
+}
+
+// Parses API keys in the format "key1:user1,key2:user2".
+func newApiKeyAuthenticator(
+	keys string,
+) *apiKeyAuthenticator {
+	a := &apiKeyAuthenticator{
+		keys: map[string]string{},
+	}
+	for _, pair := range strings.Split(keys, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			a.keys[parts[0]] = parts[1]
+		}
+	}
+	return a
+}
+
+func (a *apiKeyAuthenticator) name() string {
+	return "apikey"
+}
+
+func (a *apiKeyAuthenticator) authenticate(
+	header http.Header,
+) (
+	string,
+	bool,
+	error,
+) {
+	key := header.Get("X-API-Key")
+	if key == "" {
+		return "", false, nil
+	}
+	// Digests of equal length are compared with every key, so that neither
+	// the length nor the position of a key leaks through the timing
+	digest := sha256.Sum256([]byte(key))
+	user := ""
+	for candidate, candidateUser := range a.keys {
+		candidateDigest := sha256.Sum256([]byte(candidate))
+		if subtle.ConstantTimeCompare(candidateDigest[:], digest[:]) == 1 {
+			user = candidateUser
+		}
+	}
+	if user == "" {
+		return "", true, errInvalidApiKey
+	}
+	return user, true, nil
+}
+
+// HMAC signed JSON web tokens (HS256) whose subject is the user.
+type jwtAuthenticator struct {
+	secret []byte
+}
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+func newJwtAuthenticator(
+	secret []byte,
+) *jwtAuthenticator {
+	return &jwtAuthenticator{
+		secret: secret,
+	}
+}
+
+func (a *jwtAuthenticator) name() string {
+	return "jwt"
+}
+
+func (a *jwtAuthenticator) authenticate(
+	header http.Header,
+) (
+	string,
+	bool,
+	error,
+) {
+	authorization := header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return "", false, nil
+	}
+
+	claims, err := verifyJwt(strings.TrimPrefix(authorization, "Bearer "), a.secret)
+	if err != nil {
+		return "", true, err
+	}
+	return claims.Subject, true, nil
+}
+
+func verifyJwt(
+	token string,
+	secret []byte,
+) (
+	*jwtClaims,
+	error,
+) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	// Only HMAC SHA256 is accepted
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if json.Unmarshal(headerJson, &header) != nil || header.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+
+	claimsJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var claims jwtClaims
+	if json.Unmarshal(claimsJson, &claims) != nil || claims.Subject == "" {
+		return nil, errInvalidToken
+	}
+
+	now := time.Now().Unix()
+	if claims.ExpiresAt == 0 {
+		return nil, errInvalidToken
+	}
+	if now >= claims.ExpiresAt {
+		return nil, errExpiredToken
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return nil, errInvalidToken
+	}
+	return &claims, nil
+}
+
+// Middleware which authenticates the caller before the route handler is
+// run. Without any configured authenticators, the X-User-ID header is
+// trusted as it is.
+func authenticate(
+	next routeHandler,
+) routeHandler {
+	return func(w http.ResponseWriter, r *http.Request, scope *requestScope) {
+		ctx, err := authenticateCaller(r.Context(), r.Header, scope.serverSpan)
+		if err != nil {
+			createErrorResponse(&w, http.StatusUnauthorized, errorCodeUnauthorized, "Request is not authenticated.", scope)
+			return
+		}
+		next(w, r.WithContext(ctx), scope)
+	}
+}
+
+// Lets only the configured admin users through. Without admin users, the
+// wrapped handler is disabled for everyone.
+func authorizeAdmin(
+	next routeHandler,
+) routeHandler {
+	return func(w http.ResponseWriter, r *http.Request, scope *requestScope) {
+		user := getUser(r)
+		if !admins[user] {
+			log(logrus.WarnLevel, r.Context(), user, "Admin access is denied.")
+			scope.serverSpan.AddEvent("auth.admin_denied")
+			createErrorResponse(&w, http.StatusForbidden, errorCodeForbidden, "Request requires an admin user.", scope)
+			return
+		}
+		next(w, r, scope)
+	}
+}
+
+// Authenticates the caller and returns the context which carries the user.
+// Failures are recorded on the given server span and in the metrics.
+func authenticateCaller(
+	ctx context.Context,
+	header http.Header,
+	serverSpan trace.Span,
+) (
+	context.Context,
+	error,
+) {
+	user, method, err := authenticateHeader(header)
+	if err != nil {
+		log(logrus.WarnLevel, ctx, anonymousUser, "Authentication failed: "+err.Error())
+		serverSpan.AddEvent("auth.failure", trace.WithAttributes(
+			attribute.String("auth.method", method),
+			attribute.String("reason", err.Error()),
+		))
+		recordAuthFailure(ctx, method, err.Error())
+		return ctx, err
+	}
+
+	serverSpan.SetAttributes(semconv.EnduserID(user))
+	return context.WithValue(ctx, userKey{}, user), nil
+}
+
+func authenticateHeader(
+	header http.Header,
+) (
+	string,
+	string,
+	error,
+) {
+	if len(authenticators) == 0 {
+		user := header.Get("X-User-ID")
+		if user == "" {
+			user = anonymousUser
+		}
+		return user, "none", nil
+	}
+
+	for _, a := range authenticators {
+		user, present, err := a.authenticate(header)
+		if !present {
+			continue
+		}
+		return user, a.name(), err
+	}
+	return "", "none", errMissingCredentials
+}
+
+func getUser(
+	r *http.Request,
+) string {
+	return getUserFromContext(r.Context())
+}
+
+func getUserFromContext(
+	ctx context.Context,
+) string {
+	if user, ok := ctx.Value(userKey{}).(string); ok {
+		return user
+	}
+	return anonymousUser
+}
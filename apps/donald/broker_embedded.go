@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxBrokerPayloadSize = 1 << 20
+
+type brokerClient struct {
+	conn       net.Conn
+	writeMutex sync.Mutex
+}
+
+func (c *brokerClient) write(
+	frame []byte,
+) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+type brokerSubscription struct {
+	client  *brokerClient
+	sid     string
+	subject string
+	queue   string
+}
+
+// In-memory broker which understands the subset of the NATS protocol that
+// the messaging of joe and donald uses (CONNECT, PING, PONG, PUB, HPUB, SUB,
+// UNSUB). It allows to run the asynchronous commands without an actual NATS
+// deployment. Messages are not persisted.
+type embeddedBroker struct {
+	mutex         sync.Mutex
+	subscriptions []*brokerSubscription
+	listener      net.Listener
+}
+
+func startEmbeddedBroker(
+	address string,
+) (
+	*embeddedBroker,
+	error,
+) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &embeddedBroker{
+		listener: listener,
+	}
+	go b.serve()
+
+	logrus.Info("Embedded broker is listening on " + listener.Addr().String() + ".")
+	return b, nil
+}
+
+func (b *embeddedBroker) close() error {
+	return b.listener.Close()
+}
+
+func (b *embeddedBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handleConnection(conn)
+	}
+}
+
+func (b *embeddedBroker) handleConnection(
+	conn net.Conn,
+) {
+	client := &brokerClient{conn: conn}
+	defer func() {
+		b.removeSubscriptions(client)
+		conn.Close()
+	}()
+
+	err := client.write([]byte("INFO {\"server_id\":\"" + appName + "\",\"version\":\"2.9.0\",\"proto\":1,\"headers\":true,\"max_payload\":" + strconv.Itoa(maxBrokerPayloadSize) + "}\r\n"))
+	if err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := readNatsLine(reader)
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "CONNECT", "PONG":
+		case "PING":
+			err = client.write([]byte("PONG\r\n"))
+		case "SUB":
+			err = b.handleSubscribe(client, fields)
+		case "UNSUB":
+			if len(fields) >= 2 {
+				b.removeSubscription(client, fields[1])
+			}
+		case "PUB", "HPUB":
+			err = b.handlePublish(client, reader, fields)
+		default:
+			err = client.write([]byte("-ERR 'Unknown Protocol Operation'\r\n"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// SUB <subject> [queue group] <sid>
+func (b *embeddedBroker) handleSubscribe(
+	client *brokerClient,
+	fields []string,
+) error {
+	if len(fields) != 3 && len(fields) != 4 {
+		return client.write([]byte("-ERR 'Invalid Subscription'\r\n"))
+	}
+
+	sub := &brokerSubscription{
+		client:  client,
+		subject: fields[1],
+		sid:     fields[len(fields)-1],
+	}
+	if len(fields) == 4 {
+		sub.queue = fields[2]
+	}
+
+	b.mutex.Lock()
+	b.subscriptions = append(b.subscriptions, sub)
+	b.mutex.Unlock()
+	return nil
+}
+
+// PUB <subject> [reply-to] <#bytes>
+// HPUB <subject> [reply-to] <#header bytes> <#total bytes>
+func (b *embeddedBroker) handlePublish(
+	client *brokerClient,
+	reader *bufio.Reader,
+	fields []string,
+) error {
+	isHeaderMessage := strings.ToUpper(fields[0]) == "HPUB"
+	minFields := 3
+	if isHeaderMessage {
+		minFields = 4
+	}
+	if len(fields) < minFields {
+		return client.write([]byte("-ERR 'Invalid Publish'\r\n"))
+	}
+
+	headerSize := 0
+	totalSize, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || totalSize < 0 {
+		return client.write([]byte("-ERR 'Invalid Publish'\r\n"))
+	}
+	if totalSize > maxBrokerPayloadSize {
+		return client.write([]byte("-ERR 'Maximum Payload Violation'\r\n"))
+	}
+	if isHeaderMessage {
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+		if err != nil || headerSize < 0 || headerSize > totalSize {
+			return client.write([]byte("-ERR 'Invalid Publish'\r\n"))
+		}
+	}
+
+	payload := make([]byte, totalSize+2)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return err
+	}
+
+	b.route(fields[1], isHeaderMessage, headerSize, payload[:totalSize])
+	return nil
+}
+
+// Delivers the message to every plain subscription and to one member of
+// every queue group.
+func (b *embeddedBroker) route(
+	subject string,
+	isHeaderMessage bool,
+	headerSize int,
+	payload []byte,
+) {
+	b.mutex.Lock()
+	receivers := []*brokerSubscription{}
+	queueGroups := map[string][]*brokerSubscription{}
+	for _, sub := range b.subscriptions {
+		if !matchSubject(sub.subject, subject) {
+			continue
+		}
+		if sub.queue == "" {
+			receivers = append(receivers, sub)
+		} else {
+			queueGroups[sub.queue] = append(queueGroups[sub.queue], sub)
+		}
+	}
+	b.mutex.Unlock()
+
+	for _, members := range queueGroups {
+		receivers = append(receivers, members[rand.Intn(len(members))])
+	}
+
+	for _, sub := range receivers {
+		var controlLine string
+		if isHeaderMessage {
+			controlLine = "HMSG " + subject + " " + sub.sid + " " + strconv.Itoa(headerSize) + " " + strconv.Itoa(len(payload)) + "\r\n"
+		} else {
+			controlLine = "MSG " + subject + " " + sub.sid + " " + strconv.Itoa(len(payload)) + "\r\n"
+		}
+
+		frame := make([]byte, 0, len(controlLine)+len(payload)+2)
+		frame = append(frame, controlLine...)
+		frame = append(frame, payload...)
+		frame = append(frame, "\r\n"...)
+		err := sub.client.write(frame)
+		if err != nil {
+			logrus.Warn("Message could not be delivered: " + err.Error())
+		}
+	}
+}
+
+func (b *embeddedBroker) removeSubscription(
+	client *brokerClient,
+	sid string,
+) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	remaining := b.subscriptions[:0]
+	for _, sub := range b.subscriptions {
+		if sub.client != client || sub.sid != sid {
+			remaining = append(remaining, sub)
+		}
+	}
+	b.subscriptions = remaining
+}
+
+func (b *embeddedBroker) removeSubscriptions(
+	client *brokerClient,
+) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	remaining := b.subscriptions[:0]
+	for _, sub := range b.subscriptions {
+		if sub.client != client {
+			remaining = append(remaining, sub)
+		}
+	}
+	b.subscriptions = remaining
+}
+
+// Matches the subject against the pattern of a subscription. "*" matches a
+// single token and ">" all of the remaining tokens.
+func matchSubject(
+	pattern string,
+	subject string,
+) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, patternToken := range patternTokens {
+		if patternToken == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if patternToken != "*" && patternToken != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
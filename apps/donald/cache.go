@@ -0,0 +1,219 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultSchemaCacheSize = 1000
+)
+
+var (
+	schemaCacheBackendType      string
+	schemaCacheSize             int
+	schemaCacheTtl              time.Duration
+	schemaCacheWarmup           bool
+	schemaCacheWarmupUsers      string
+	schemaComputationIterations int
+
+	cache schemaCacheBackend
+)
+
+// Storage of the processing schemas which are consulted in postprocessing.
+type schemaCacheBackend interface {
+	get(ctx context.Context, key string) (string, bool, error)
+	set(ctx context.Context, key string, schema string) error
+	invalidate(ctx context.Context, key string) error
+}
+
+type schemaCacheEntry struct {
+	key       string
+	schema    string
+	expiresAt time.Time
+}
+
+// In-process LRU cache for the processing schemas with a TTL per entry and
+// an upper limit for the number of entries.
+type schemaCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+func newSchemaCache(
+	maxEntries int,
+	ttl time.Duration,
+) *schemaCache {
+	return &schemaCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (c *schemaCache) get(
+	ctx context.Context,
+	key string,
+) (
+	string,
+	bool,
+	error,
+) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+
+	entry := elem.Value.(*schemaCacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.removeElement(ctx, elem, "expired")
+		return "", false, nil
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.schema, true, nil
+}
+
+func (c *schemaCache) set(
+	ctx context.Context,
+	key string,
+	schema string,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*schemaCacheEntry)
+		entry.schema = schema
+		entry.expiresAt = expiresAt
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+
+	c.entries[key] = c.lru.PushFront(&schemaCacheEntry{
+		key:       key,
+		schema:    schema,
+		expiresAt: expiresAt,
+	})
+
+	// Evict the least recently used entries when the cache is full
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.removeElement(ctx, c.lru.Back(), "size")
+	}
+	return nil
+}
+
+func (c *schemaCache) invalidate(
+	ctx context.Context,
+	key string,
+) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(ctx, elem, "invalidated")
+	}
+	return nil
+}
+
+func (c *schemaCache) removeElement(
+	ctx context.Context,
+	elem *list.Element,
+	reason string,
+) {
+	entry := elem.Value.(*schemaCacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+
+	attrs := []attribute.KeyValue{
+		attribute.String("schema.key", entry.key),
+		attribute.String("reason", reason),
+	}
+	trace.SpanFromContext(ctx).AddEvent("schema.cache.eviction", trace.WithAttributes(attrs...))
+	schemaCacheEvictions.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+func createSchemaCache() schemaCacheBackend {
+	var c schemaCacheBackend
+	switch schemaCacheBackendType {
+	case "redis":
+		c = newRedisSchemaCache(redisServer+":"+redisPort, schemaCacheTtl)
+		logrus.Info("Schema cache is using Redis at " + redisServer + ":" + redisPort + ".")
+	default:
+		// The keys are derived from the users, so the cache is always bounded
+		size := schemaCacheSize
+		if size <= 0 {
+			size = defaultSchemaCacheSize
+		}
+		c = newSchemaCache(size, schemaCacheTtl)
+	}
+
+	if schemaCacheWarmup {
+		warmupSchemaCache(c)
+	}
+	return c
+}
+
+// Precomputes the schemas of the configured users for every allowed method
+// so that the first requests after startup do not have to calculate them
+// from scratch.
+func warmupSchemaCache(
+	c schemaCacheBackend,
+) {
+	ctx := context.Background()
+	users := []string{anonymousUser}
+	if schemaCacheWarmupUsers != "" {
+		users = strings.Split(schemaCacheWarmupUsers, ",")
+	}
+
+	for _, user := range users {
+		for _, httpMethod := range []string{http.MethodGet, http.MethodDelete} {
+			key := getSchemaKey(httpMethod, strings.TrimSpace(user))
+			err := c.set(ctx, key, computeSchema(key))
+			if err != nil {
+				logrus.Warn("Schema cache could not be warmed up: " + err.Error())
+				return
+			}
+		}
+	}
+	logrus.Info("Schema cache is warmed up with " + strconv.Itoa(len(users)*2) + " entries.")
+}
+
+func getSchemaKey(
+	httpMethod string,
+	user string,
+) string {
+	return mysqlTable + ":" + httpMethod + ":" + user
+}
+
+// Calculates the processing schema from scratch. The cost scales with the
+// configured number of iterations.
+func computeSchema(
+	key string,
+) string {
+	sum := sha256.Sum256([]byte(key))
+	for i := 0; i < schemaComputationIterations; i++ {
+		sum = sha256.Sum256(sum[:])
+	}
+	return hex.EncodeToString(sum[:])
+}
@@ -18,13 +18,21 @@ var (
 	db *sql.DB
 )
 
-func createDatabaseConnection() *sql.DB {
+// Connects to the database which is created if it does not exist yet. When
+// the database is only inspected, nothing is created and the connection
+// does not select the database, so that a missing database can be told.
+func createDatabaseConnection(
+	readOnly bool,
+) *sql.DB {
 	// Connect to MySQL
 	datasourceName := mysqlUsername + ":" + mysqlPassword + "@tcp(" + mysqlServer + ":" + mysqlPort + ")/"
-	db, err := sql.Open("mysql", datasourceName)
+	db, err := sql.Open(faultDriverName, datasourceName)
 	if err != nil {
 		panic(err)
 	}
+	if readOnly {
+		return db
+	}
 
 	// Create the database
 	_, err = db.Exec("CREATE DATABASE IF NOT EXISTS " + mysqlDatabase)
@@ -35,18 +43,10 @@ func createDatabaseConnection() *sql.DB {
 	logrus.Info("Database is created successfully!")
 
 	// Use the database
-	db, err = sql.Open("mysql", datasourceName+mysqlDatabase)
-	if err != nil {
-		panic(err)
-	}
-
-	// Create the table
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + mysqlTable + " (id INT NOT NULL PRIMARY KEY AUTO_INCREMENT, name VARCHAR(50) NOT NULL)")
+	db, err = sql.Open(faultDriverName, datasourceName+mysqlDatabase)
 	if err != nil {
 		panic(err)
 	}
 
-	logrus.Info("Table is created successfully!")
-
 	return db
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultEnrichmentTimeout = 30 * time.Second
+)
+
+var (
+	enrichmentEndpoint string
+	enrichmentPort     string
+	enrichmentTimeout  time.Duration
+
+	enrichmentClient *http.Client
+)
+
+// Creates the client for the enrichment service. A hanging service must not
+// hold the postprocessing up for longer than the timeout.
+func createEnrichmentClient() {
+	timeout := enrichmentTimeout
+	if timeout <= 0 {
+		timeout = defaultEnrichmentTimeout
+	}
+
+	enrichmentClient = &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		Timeout:   timeout,
+	}
+}
+
+// Enriches the names of the user with the attributes of the enrichment
+// service when it is configured. The postprocessing does not fail with the
+// enrichment, a failure is only reported as span event and warning.
+func performEnrichment(
+	ctx context.Context,
+	user string,
+) {
+	if enrichmentEndpoint == "" {
+		return
+	}
+
+	log(logrus.InfoLevel, ctx, user, "Enriching...")
+
+	err := callEnrichment(ctx, user)
+	if err != nil {
+		log(logrus.WarnLevel, ctx, user, "Enrichment failed: "+err.Error())
+		trace.SpanFromContext(ctx).AddEvent("enrichment.failure", trace.WithAttributes(
+			attribute.String("reason", err.Error()),
+		))
+		return
+	}
+
+	log(logrus.InfoLevel, ctx, user, "Enrichment is complete.")
+}
+
+func callEnrichment(
+	ctx context.Context,
+	user string,
+) error {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet,
+		"http://"+enrichmentEndpoint+":"+enrichmentPort+"/api/enrich",
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-User-ID", user)
+
+	res, err := enrichmentClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.New(string(resBody))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	errorCodeNotFound               = "not_found"
+	errorCodeMethodNotAllowed       = "method_not_allowed"
+	errorCodeInvalidId              = "invalid_id"
+	errorCodeDatabaseConnectionLost = "database_connection_lost"
+	errorCodeTableNotFound          = "table_not_found"
+	errorCodeDatabaseError          = "database_error"
+	errorCodeLockWaitTimeout        = "lock_wait_timeout"
+	errorCodeDeadlock               = "deadlock"
+)
+
+// Body of every failed response so that the callers can tell what went
+// wrong and in which trace.
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	TraceId string `json:"traceId,omitempty"`
+}
+
+func createErrorResponse(
+	w *http.ResponseWriter,
+	statusCode int,
+	code string,
+	message string,
+	scope *requestScope,
+) {
+	res := errorResponse{
+		Code:    code,
+		Message: message,
+		TraceId: scope.traceId(),
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		body = []byte(message)
+	}
+
+	(*w).Header().Set("Content-Type", "application/json")
+	createHttpResponse(w, statusCode, body)
+}
+
+func getDbErrorCode(
+	err error,
+) string {
+	switch getDbErrorKind(err) {
+	case "table_not_found":
+		return errorCodeTableNotFound
+	case "lock_wait_timeout":
+		return errorCodeLockWaitTimeout
+	case "deadlock":
+		return errorCodeDeadlock
+	default:
+		return errorCodeDatabaseError
+	}
+}
+
+// Responds with the error of the database query. A lost connection is
+// reported as unavailable, every other error as internal server error.
+func createQueryErrorResponse(
+	w *http.ResponseWriter,
+	err error,
+	scope *requestScope,
+) {
+	if isConnectionLost(err) {
+		createErrorResponse(w, http.StatusServiceUnavailable, errorCodeDatabaseConnectionLost, "Connection to database is lost.", scope)
+		return
+	}
+	createErrorResponse(w, http.StatusInternalServerError, getDbErrorCode(err), err.Error(), scope)
+}
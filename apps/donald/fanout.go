@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	concurrentProcessing bool
+)
+
+// Independent part of a request which runs in its own goroutine.
+type processingBranch struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Performs the list query, the count query and the postprocessing of a read
+// request concurrently. The first failing branch cancels the others.
+func processNameRequestConcurrently(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) (
+	*nameResult,
+	error,
+) {
+	result := &nameResult{}
+	err := performBranches(ctx, scope,
+		&processingBranch{
+			name: "list",
+			run: func(ctx context.Context) error {
+				listResult, err := performQuery(ctx, scope, req)
+				if err != nil {
+					return err
+				}
+				result.names = listResult.names
+				return nil
+			},
+		},
+		&processingBranch{
+			name: "count",
+			run: func(ctx context.Context) error {
+				count, err := performCountQuery(ctx, scope, req)
+				if err != nil {
+					return err
+				}
+				result.count = count
+				return nil
+			},
+		},
+		&processingBranch{
+			name: "postprocessing",
+			run: func(ctx context.Context) error {
+				performPostprocessing(ctx, scope, req)
+				return ctx.Err()
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	scope.serverSpan.SetAttributes(attribute.Int64("names.count", result.count))
+	return result, nil
+}
+
+// Runs the branches concurrently within child spans of their own and waits
+// for all of them. The context of the remaining branches is cancelled as
+// soon as one of them fails and the first error is returned.
+func performBranches(
+	ctx context.Context,
+	scope *requestScope,
+	branches ...*processingBranch,
+) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, branch := range branches {
+		branch := branch
+		group.Go(func() error {
+			branchCtx, branchSpan := scope.startInternalSpan(groupCtx, branch.name+" branch",
+				attribute.String("branch.name", branch.name),
+			)
+			defer branchSpan.End()
+
+			err := branch.run(branchCtx)
+			if err == nil {
+				return nil
+			}
+
+			// Distinguish the branches which were cancelled by a failing one
+			if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+				branchSpan.AddEvent("branch.cancelled")
+				log(logrus.WarnLevel, branchCtx, getUserFromContext(ctx), "Branch "+branch.name+" is cancelled.")
+			} else {
+				setSpanError(branchSpan, err.Error())
+			}
+			return err
+		})
+	}
+	return group.Wait()
+}
+
+func performCountQuery(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) (
+	int64,
+	error,
+) {
+	dbOperation := "SELECT"
+	dbStatement := dbOperation + " COUNT(*) FROM " + mysqlTable
+	dbArgs := []interface{}{}
+	if req.id != "" {
+		dbStatement += " WHERE id = ?"
+		dbArgs = append(dbArgs, req.id)
+	}
+
+	if !considerDatabaseSpans {
+		return executeCountQuery(ctx, req.user, dbOperation, dbStatement, dbArgs...)
+	}
+
+	// Set additional span attributes
+	dbSpanAttrs := getCommonDbSpanAttributes()
+	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
+	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.statement", dbStatement))
+
+	var dbSpan trace.Span
+	ctx, dbSpan = scope.startClientSpan(
+		ctx,
+		dbOperation+" "+mysqlDatabase+"."+mysqlTable,
+		dbSpanAttrs...,
+	)
+	defer dbSpan.End()
+
+	count, err := executeCountQuery(ctx, req.user, dbOperation, dbStatement, dbArgs...)
+	if err != nil {
+		setSpanError(dbSpan, err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+func executeCountQuery(
+	ctx context.Context,
+	user string,
+	dbOperation string,
+	dbStatement string,
+	dbArgs ...interface{},
+) (
+	int64,
+	error,
+) {
+	log(logrus.InfoLevel, ctx, user, "Counting names...")
+
+	// Start timer
+	queryStartTime := time.Now()
+	defer recordDbQueryDuration(ctx, dbOperation, queryStartTime)
+
+	var count int64
+	err := db.QueryRowContext(ctx, dbStatement, dbArgs...).Scan(&count)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		recordDbError(ctx, dbOperation, getDbErrorKind(err))
+		return 0, err
+	}
+
+	log(logrus.InfoLevel, ctx, user, "Names are counted.")
+	return count, nil
+}
+
+// Read requests are processed concurrently when it is enabled. Writes stay
+// sequential since their postprocessing depends on the outcome of the query.
+func isConcurrentRequest(
+	req *nameRequest,
+) bool {
+	return concurrentProcessing && req.method == http.MethodGet
+}
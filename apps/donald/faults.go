@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	faultDriverName = "mysql-faults"
+
+	faultKindDial    = "dial"
+	faultKindDrop    = "drop"
+	faultKindLatency = "latency"
+	faultKindError   = "error"
+)
+
+var (
+	faultDialRate    int
+	faultDropRate    int
+	faultLatencyRate int
+	faultLatency     time.Duration
+	faultErrorRate   int
+	faultErrorCode   int
+
+	errInjectedFault = errors.New("connection refused (injected)")
+)
+
+type faultTriggerKey struct{}
+type faultsDisabledKey struct{}
+
+func init() {
+	sql.Register(faultDriverName, &faultDriver{base: mysql.MySQLDriver{}})
+}
+
+// Forces the fault for every database call which is made with the context
+// regardless of the configured rates.
+func withFaultTrigger(
+	ctx context.Context,
+	kind string,
+) context.Context {
+	return context.WithValue(ctx, faultTriggerKey{}, kind)
+}
+
+// Keeps the database calls which are made with the context free from the
+// random faults, e.g. the ones of the migrations and the seeding.
+func withoutFaults(
+	ctx context.Context,
+) context.Context {
+	return context.WithValue(ctx, faultsDisabledKey{}, true)
+}
+
+// Wraps the MySQL driver and injects faults into the dials and the calls of
+// the connections. The faults are either triggered by the context or happen
+// by the configured rates in percent. The errors are the ones of the driver
+// itself, so database/sql handles them as usual (e.g. removes the broken
+// connections from the pool).
+type faultDriver struct {
+	base mysql.MySQLDriver
+}
+
+func (d *faultDriver) Open(
+	dsn string,
+) (
+	driver.Conn,
+	error,
+) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(context.Background())
+}
+
+func (d *faultDriver) OpenConnector(
+	dsn string,
+) (
+	driver.Connector,
+	error,
+) {
+	base, err := d.base.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConnector{driver: d, base: base}, nil
+}
+
+type faultConnector struct {
+	driver *faultDriver
+	base   driver.Connector
+}
+
+func (c *faultConnector) Connect(
+	ctx context.Context,
+) (
+	driver.Conn,
+	error,
+) {
+	if shouldInjectFault(ctx, faultKindDial, faultDialRate) {
+		recordInjectedFault(ctx, faultKindDial)
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errInjectedFault}
+	}
+
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{base: conn}, nil
+}
+
+func (c *faultConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Connection which may break, stall or fail before its calls reach MySQL.
+type faultConn struct {
+	base driver.Conn
+
+	mutex  sync.Mutex
+	broken bool
+}
+
+func (c *faultConn) isBroken() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.broken
+}
+
+// Injects the faults before a call and tells whether the connection is to be
+// dropped after the call has reached MySQL.
+func (c *faultConn) inject(
+	ctx context.Context,
+) (
+	bool,
+	error,
+) {
+	if c.isBroken() {
+		return false, driver.ErrBadConn
+	}
+
+	if shouldInjectFault(ctx, faultKindLatency, faultLatencyRate) {
+		recordInjectedFault(ctx, faultKindLatency)
+		select {
+		case <-time.After(faultLatency):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	if shouldInjectFault(ctx, faultKindDrop, faultDropRate) {
+		recordInjectedFault(ctx, faultKindDrop)
+		return true, nil
+	}
+
+	if shouldInjectFault(ctx, faultKindError, faultErrorRate) {
+		recordInjectedFault(ctx, faultKindError)
+		return false, &mysql.MySQLError{
+			Number:  uint16(faultErrorCode),
+			Message: "Injected fault",
+		}
+	}
+	return false, nil
+}
+
+// Drops the connection while the caller still waits for the result of its
+// call. The connection is closed for real and stays broken so that the pool
+// discards it. The error is not the one which database/sql retries silently
+// on another connection, the caller sees the connection loss.
+func (c *faultConn) drop() error {
+	c.mutex.Lock()
+	c.broken = true
+	c.mutex.Unlock()
+	c.base.Close()
+	return mysql.ErrInvalidConn
+}
+
+func (c *faultConn) Prepare(
+	query string,
+) (
+	driver.Stmt,
+	error,
+) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *faultConn) PrepareContext(
+	ctx context.Context,
+	query string,
+) (
+	driver.Stmt,
+	error,
+) {
+	if c.isBroken() {
+		return nil, driver.ErrBadConn
+	}
+	return c.base.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *faultConn) Close() error {
+	if c.isBroken() {
+		return nil
+	}
+	return c.base.Close()
+}
+
+func (c *faultConn) Begin() (
+	driver.Tx,
+	error,
+) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultConn) BeginTx(
+	ctx context.Context,
+	opts driver.TxOptions,
+) (
+	driver.Tx,
+	error,
+) {
+	drop, err := c.inject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if drop {
+		return nil, c.drop()
+	}
+	return c.base.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *faultConn) ExecContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (
+	driver.Result,
+	error,
+) {
+	drop, err := c.inject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The statement is executed, but its result gets lost with the connection
+	result, err := c.base.(driver.ExecerContext).ExecContext(ctx, query, args)
+	if drop {
+		return nil, c.drop()
+	}
+	return result, err
+}
+
+func (c *faultConn) QueryContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (
+	driver.Rows,
+	error,
+) {
+	drop, err := c.inject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The query is executed, but its rows get lost with the connection
+	rows, err := c.base.(driver.QueryerContext).QueryContext(ctx, query, args)
+	if drop {
+		if err == nil {
+			rows.Close()
+		}
+		return nil, c.drop()
+	}
+	return rows, err
+}
+
+func (c *faultConn) Ping(
+	ctx context.Context,
+) error {
+	if c.isBroken() {
+		return driver.ErrBadConn
+	}
+	return c.base.(driver.Pinger).Ping(ctx)
+}
+
+func (c *faultConn) ResetSession(
+	ctx context.Context,
+) error {
+	if c.isBroken() {
+		return driver.ErrBadConn
+	}
+	return c.base.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *faultConn) IsValid() bool {
+	return !c.isBroken() && c.base.(driver.Validator).IsValid()
+}
+
+func (c *faultConn) CheckNamedValue(
+	nv *driver.NamedValue,
+) error {
+	return c.base.(driver.NamedValueChecker).CheckNamedValue(nv)
+}
+
+func shouldInjectFault(
+	ctx context.Context,
+	kind string,
+	rate int,
+) bool {
+	if trigger, ok := ctx.Value(faultTriggerKey{}).(string); ok && trigger == kind {
+		return true
+	}
+	if disabled, _ := ctx.Value(faultsDisabledKey{}).(bool); disabled {
+		return false
+	}
+	return rate > 0 && rand.Intn(100) < rate
+}
+
+func recordInjectedFault(
+	ctx context.Context,
+	kind string,
+) {
+	trace.SpanFromContext(ctx).AddEvent("db.fault.injected", trace.WithAttributes(
+		attribute.String("kind", kind),
+	))
+	dbFaults.Add(ctx, 1, metric.WithAttributes(attribute.String("kind", kind)))
+}
+
+// Reports whether the connection to the database broke or could not be
+// established at all.
+func isConnectionLost(
+	err error,
+) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.As(err, &netErr)
+}
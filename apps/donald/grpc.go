@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-berlin-event-2023-02-16/apps/donald/pb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	errorCodeInvalidName = "invalid_name"
+
+	maxNameLength = 50
+)
+
+var (
+	grpcPort string
+)
+
+// gRPC counterpart of the HTTP API. The requests are processed the same way
+// as their HTTP equivalents.
+type nameServer struct {
+	pb.UnimplementedNameServiceServer
+}
+
+// Serves the gRPC API when a port is configured. The server spans are
+// created by the otelgrpc interceptor before the caller is authenticated.
+func startGrpcServer() {
+	if grpcPort == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		panic(err)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(),
+			authenticateGrpc,
+		),
+	}
+	if tlsConfig := createServerTlsConfig(); tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	pb.RegisterNameServiceServer(server, &nameServer{})
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil {
+			logrus.Error("gRPC server stopped: " + err.Error())
+		}
+	}()
+
+	logrus.Info("gRPC server is listening on port " + grpcPort + ".")
+}
+
+// Authenticates the caller with the request metadata just like the HTTP
+// middleware does with the request headers.
+func authenticateGrpc(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (
+	interface{},
+	error,
+) {
+	header := http.Header{}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	ctx, err := authenticateCaller(ctx, header, trace.SpanFromContext(ctx))
+	if err != nil {
+		return nil, newGrpcError(codes.Unauthenticated, errorCodeUnauthorized, "Request is not authenticated.")
+	}
+	return handler(ctx, req)
+}
+
+func (s *nameServer) ListNames(
+	ctx context.Context,
+	in *pb.ListNamesRequest,
+) (
+	*pb.ListNamesResponse,
+	error,
+) {
+	req, err := newGrpcNameRequest(ctx, http.MethodGet, in.GetId(), "", in.GetSimulation())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := processNameRequest(ctx, getRequestScope(ctx), req)
+	if err != nil {
+		return nil, getQueryGrpcError(err)
+	}
+	return &pb.ListNamesResponse{Names: result.names}, nil
+}
+
+func (s *nameServer) DeleteNames(
+	ctx context.Context,
+	in *pb.DeleteNamesRequest,
+) (
+	*pb.DeleteNamesResponse,
+	error,
+) {
+	req, err := newGrpcNameRequest(ctx, http.MethodDelete, in.GetId(), "", in.GetSimulation())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := processNameRequest(ctx, getRequestScope(ctx), req)
+	if err != nil {
+		return nil, getQueryGrpcError(err)
+	}
+	return &pb.DeleteNamesResponse{Deleted: result.rowsAffected}, nil
+}
+
+func (s *nameServer) CreateName(
+	ctx context.Context,
+	in *pb.CreateNameRequest,
+) (
+	*pb.CreateNameResponse,
+	error,
+) {
+	if in.GetName() == "" || len(in.GetName()) > maxNameLength {
+		return nil, newGrpcError(codes.InvalidArgument, errorCodeInvalidName, "Name must have between 1 and "+strconv.Itoa(maxNameLength)+" characters.")
+	}
+
+	req, err := newGrpcNameRequest(ctx, http.MethodPost, 0, in.GetName(), in.GetSimulation())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := processNameRequest(ctx, getRequestScope(ctx), req)
+	if err != nil {
+		return nil, getQueryGrpcError(err)
+	}
+	return &pb.CreateNameResponse{Id: result.lastInsertId}, nil
+}
+
+// Builds the request with the HTTP method which the RPC corresponds to so
+// that both APIs share the processing schemas.
+func newGrpcNameRequest(
+	ctx context.Context,
+	method string,
+	id int64,
+	name string,
+	simulation *pb.Simulation,
+) (
+	*nameRequest,
+	error,
+) {
+	if id < 0 {
+		return nil, newGrpcError(codes.InvalidArgument, errorCodeInvalidId, "Provided id must not be negative.")
+	}
+
+	user := getUserFromContext(ctx)
+	log(logrus.InfoLevel, ctx, user, "Handler is triggered")
+
+	req := &nameRequest{
+		method:                       method,
+		user:                         user,
+		name:                         name,
+		databaseConnectionError:      simulation.GetDatabaseConnectionError(),
+		tableDoesNotExistError:       simulation.GetTableDoesNotExistError(),
+		schemaNotFoundInCacheWarning: simulation.GetSchemaNotFoundInCacheWarning(),
+		slowQuery:                    simulation.GetSlowQuery(),
+		lockContention:               simulation.GetLockContention(),
+		deadlock:                     simulation.GetDeadlock(),
+	}
+	if id > 0 {
+		req.id = strconv.FormatInt(id, 10)
+	}
+	return req, nil
+}
+
+// Creates a status error which carries the error code of donald as reason
+// so that the callers can handle it like the HTTP error responses.
+func newGrpcError(
+	code codes.Code,
+	errorCode string,
+	message string,
+) error {
+	st := status.New(code, message)
+	stWithDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: errorCode,
+		Domain: appName,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return stWithDetails.Err()
+}
+
+func getQueryGrpcError(
+	err error,
+) error {
+	if isConnectionLost(err) {
+		return newGrpcError(codes.Unavailable, errorCodeDatabaseConnectionLost, "Connection to database is lost.")
+	}
+	return newGrpcError(codes.Internal, getDbErrorCode(err), err.Error())
+}
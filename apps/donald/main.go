@@ -5,9 +5,9 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 var (
@@ -16,6 +16,7 @@ var (
 
 	considerDatabaseSpans       bool
 	considerPostprocessingSpans bool
+	considerCacheSpans          bool
 
 	logLevel       string
 	logWithContext bool
@@ -40,21 +41,148 @@ func main() {
 	mp := newMetricProvider(ctx)
 	defer shutdownMetricProvider(ctx, mp)
 
+	// Create custom instruments
+	createInstruments()
+
+	// Run a migrate command instead of serving requests
+	migrateCommand := ""
+	if len(os.Args) == 3 && os.Args[1] == "migrate" {
+		migrateCommand = os.Args[2]
+	}
+
 	// Connect to MySQL
-	db = createDatabaseConnection()
+	db = createDatabaseConnection(isReadOnlyMigrateCommand(migrateCommand))
 	defer db.Close()
-
-	// Serve
-	http.Handle("/api", otelhttp.NewHandler(http.HandlerFunc(handler), "api"))
-	http.ListenAndServe(":"+appPort, nil)
+	createDatabaseInstruments()
+
+	if migrateCommand != "" {
+		err := runMigrateCommand(migrateCommand)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	// Migrate database
+	err := migrateDatabase(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	// Seed database
+	seedDatabaseOnStartup()
+
+	// Start in-memory Redis server
+	if useFakeRedisServer {
+		fakeRedis, err := startFakeRedisServer(redisServer + ":" + redisPort)
+		if err != nil {
+			panic(err)
+		}
+		defer fakeRedis.close()
+	}
+
+	// Create schema cache
+	cache = createSchemaCache()
+
+	// Create authenticators
+	createAuthenticators()
+
+	// Create enrichment client
+	createEnrichmentClient()
+
+	// Start embedded broker
+	if messagingEmbedded {
+		embeddedBroker, err := startEmbeddedBroker(":" + messagingPort)
+		if err != nil {
+			panic(err)
+		}
+		defer embeddedBroker.close()
+	}
+
+	// Consume commands
+	startCommandConsumer()
+
+	// Serve gRPC
+	startGrpcServer()
+
+	// Serve HTTP
+	router := newRouter()
+	router.handle(http.MethodGet, "/api", authenticate(handler))
+	router.handle(http.MethodDelete, "/api", authenticate(handler))
+	router.handle(http.MethodGet, "/api/{id}", authenticate(handler))
+	router.handle(http.MethodDelete, "/api/{id}", authenticate(handler))
+	router.handle(http.MethodPost, "/admin/seed", authenticate(authorizeAdmin(seedHandler)))
+	listenAndServe(router.handler())
 }
 
 func parseFlags() {
 	appName = os.Getenv("APP_NAME")
 	appPort = os.Getenv("APP_PORT")
+	grpcPort = os.Getenv("GRPC_PORT")
 
 	considerDatabaseSpans, _ = strconv.ParseBool(os.Getenv("CONSIDER_DATABASE_SPANS"))
 	considerPostprocessingSpans, _ = strconv.ParseBool(os.Getenv("CONSIDER_POSTPROCESSING_SPANS"))
+	considerCacheSpans, _ = strconv.ParseBool(os.Getenv("CONSIDER_CACHE_SPANS"))
+	concurrentProcessing, _ = strconv.ParseBool(os.Getenv("CONCURRENT_PROCESSING"))
+
+	migrationsLockTimeoutInMs, _ := strconv.ParseInt(os.Getenv("MIGRATIONS_LOCK_TIMEOUT"), 10, 64)
+	migrationsLockTimeout = time.Duration(migrationsLockTimeoutInMs) * time.Millisecond
+
+	seedOnStartup, _ = strconv.ParseBool(os.Getenv("SEED_ON_STARTUP"))
+	seedRows, _ = strconv.Atoi(os.Getenv("SEED_ROWS"))
+	seedValue, _ = strconv.ParseInt(os.Getenv("SEED_VALUE"), 10, 64)
+	seedBatchSize, _ = strconv.Atoi(os.Getenv("SEED_BATCH_SIZE"))
+
+	lockHoldDurationInMs, _ := strconv.ParseInt(os.Getenv("LOCK_HOLD_DURATION"), 10, 64)
+	lockHoldDuration = time.Duration(lockHoldDurationInMs) * time.Millisecond
+	lockWaitTimeoutInMs, _ := strconv.ParseInt(os.Getenv("LOCK_WAIT_TIMEOUT"), 10, 64)
+	lockWaitTimeout = time.Duration(lockWaitTimeoutInMs) * time.Millisecond
+
+	faultDialRate, _ = strconv.Atoi(os.Getenv("FAULT_DIAL_RATE"))
+	faultDropRate, _ = strconv.Atoi(os.Getenv("FAULT_DROP_RATE"))
+	faultLatencyRate, _ = strconv.Atoi(os.Getenv("FAULT_LATENCY_RATE"))
+	faultLatencyInMs, _ := strconv.ParseInt(os.Getenv("FAULT_LATENCY"), 10, 64)
+	faultLatency = time.Duration(faultLatencyInMs) * time.Millisecond
+	faultErrorRate, _ = strconv.Atoi(os.Getenv("FAULT_ERROR_RATE"))
+	faultErrorCode, _ = strconv.Atoi(os.Getenv("FAULT_ERROR_CODE"))
+
+	schemaCacheBackendType = os.Getenv("SCHEMA_CACHE_BACKEND")
+	redisServer = os.Getenv("REDIS_SERVER")
+	redisPort = os.Getenv("REDIS_PORT")
+	useFakeRedisServer, _ = strconv.ParseBool(os.Getenv("REDIS_FAKE"))
+
+	enrichmentEndpoint = os.Getenv("ENRICHMENT_ENDPOINT")
+	enrichmentPort = os.Getenv("ENRICHMENT_PORT")
+	enrichmentTimeoutInMs, _ := strconv.ParseInt(os.Getenv("ENRICHMENT_TIMEOUT"), 10, 64)
+	enrichmentTimeout = time.Duration(enrichmentTimeoutInMs) * time.Millisecond
+
+	messagingBackend = os.Getenv("MESSAGING_BACKEND")
+	messagingServer = os.Getenv("MESSAGING_SERVER")
+	messagingPort = os.Getenv("MESSAGING_PORT")
+	messagingEmbedded, _ = strconv.ParseBool(os.Getenv("MESSAGING_EMBEDDED"))
+	messagingSubject = os.Getenv("MESSAGING_SUBJECT")
+
+	schemaCacheSize, _ = strconv.Atoi(os.Getenv("SCHEMA_CACHE_SIZE"))
+	schemaCacheTtlInMs, _ := strconv.ParseInt(os.Getenv("SCHEMA_CACHE_TTL"), 10, 64)
+	schemaCacheTtl = time.Duration(schemaCacheTtlInMs) * time.Millisecond
+	schemaCacheWarmup, _ = strconv.ParseBool(os.Getenv("SCHEMA_CACHE_WARMUP"))
+	schemaCacheWarmupUsers = os.Getenv("SCHEMA_CACHE_WARMUP_USERS")
+	schemaComputationIterations, _ = strconv.Atoi(os.Getenv("SCHEMA_COMPUTATION_ITERATIONS"))
+
+	authMethods = os.Getenv("AUTH_METHODS")
+	authApiKeys = os.Getenv("AUTH_API_KEYS")
+	authJwtSecret = os.Getenv("AUTH_JWT_SECRET")
+	adminUsers = os.Getenv("ADMIN_USERS")
+
+	tlsCertFile = os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile = os.Getenv("TLS_KEY_FILE")
+	tlsClientCaFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	tlsReloadIntervalInMs, _ := strconv.ParseInt(os.Getenv("TLS_RELOAD_INTERVAL"), 10, 64)
+	tlsReloadInterval = time.Duration(tlsReloadIntervalInMs) * time.Millisecond
+
+	metricViewsFile = os.Getenv("METRIC_VIEWS_FILE")
+	metricExporters = os.Getenv("METRIC_EXPORTERS")
+	prometheusPort = os.Getenv("PROMETHEUS_PORT")
 
 	logLevel = os.Getenv("LOG_LEVEL")
 	logWithContext, _ = strconv.ParseBool(os.Getenv("LOG_WITH_CONTEXT"))
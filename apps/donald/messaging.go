@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	commandTypeDelete  = "DELETE"
+	commandTypeCreate  = "CREATE"
+	commandTypeUnknown = "unknown"
+)
+
+var (
+	messagingBackend  string
+	messagingServer   string
+	messagingPort     string
+	messagingEmbedded bool
+	messagingSubject  string
+
+	broker messageBroker
+)
+
+// Backend of the asynchronous commands. Further backends (e.g. Kafka
+// compatible ones) only need to implement this interface.
+type messageBroker interface {
+	name() string
+	publish(subject string, header map[string]string, data []byte) error
+	subscribe(subject string, queue string, handler func(*brokerMessage)) error
+	close() error
+}
+
+// Command which joe publishes instead of calling donald synchronously.
+type donaldCommand struct {
+	Type        string            `json:"type"`
+	Id          int64             `json:"id,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+	PublishedAt int64             `json:"publishedAt"`
+}
+
+func createMessageBroker() messageBroker {
+	switch messagingBackend {
+	case "":
+		return nil
+	case "nats":
+		return newNatsBroker(messagingServer + ":" + messagingPort)
+	default:
+		panic("unknown messaging backend: " + messagingBackend)
+	}
+}
+
+// Consumes the commands of joe when a messaging backend is configured. All
+// replicas of donald share the commands through the same queue group.
+func startCommandConsumer() {
+	broker = createMessageBroker()
+	if broker == nil {
+		return
+	}
+
+	err := broker.subscribe(messagingSubject, appName, consumeCommand)
+	if err != nil {
+		logrus.Warn("Commands could not be subscribed yet: " + err.Error())
+		return
+	}
+	logrus.Info("Commands are consumed from " + messagingSubject + ".")
+}
+
+func consumeCommand(
+	msg *brokerMessage,
+) {
+	// Continue the trace of the producer
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(msg.header))
+	ctx, span := otel.GetTracerProvider().Tracer(appName).Start(
+		ctx,
+		msg.subject+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(broker.name()),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingDestinationName(msg.subject),
+			semconv.MessagingMessagePayloadSizeBytes(len(msg.data)),
+			semconv.MessagingConsumerID(appName),
+		),
+	)
+	defer span.End()
+
+	scope := newRequestScope(span)
+	ctx = context.WithValue(ctx, requestScopeKey{}, scope)
+
+	// Start timer
+	processingStartTime := time.Now()
+
+	commandType, err := processCommand(ctx, scope, msg)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, getUserFromContext(ctx), "Command could not be processed: "+err.Error())
+		setSpanError(span, err.Error())
+	}
+	recordCommandProcessing(ctx, msg.subject, commandType, err == nil, processingStartTime)
+}
+
+func processCommand(
+	ctx context.Context,
+	scope *requestScope,
+	msg *brokerMessage,
+) (
+	string,
+	error,
+) {
+	var command donaldCommand
+	err := json.Unmarshal(msg.data, &command)
+	if err != nil {
+		return commandTypeUnknown, err
+	}
+
+	// The type comes from the message, only the known ones become attributes
+	commandType := getKnownCommandType(command.Type)
+	scope.serverSpan.SetAttributes(attribute.String("command.type", commandType))
+	recordCommandLag(ctx, msg.subject, commandType, command.PublishedAt)
+
+	// Authenticate with the credentials of the message
+	header := http.Header{}
+	for key, value := range msg.header {
+		header.Set(key, value)
+	}
+	ctx, err = authenticateCaller(ctx, header, scope.serverSpan)
+	if err != nil {
+		return commandType, err
+	}
+
+	req := &nameRequest{
+		user:                         getUserFromContext(ctx),
+		name:                         command.Name,
+		databaseConnectionError:      command.Parameters["databaseConnectionError"] == "true",
+		tableDoesNotExistError:       command.Parameters["tableDoesNotExistError"] == "true",
+		schemaNotFoundInCacheWarning: command.Parameters["schemaNotFoundInCacheWarning"] == "true",
+		slowQuery:                    command.Parameters["slowQuery"] == "true",
+		lockContention:               command.Parameters["lockContention"] == "true",
+		deadlock:                     command.Parameters["deadlock"] == "true",
+	}
+	if command.Id > 0 {
+		req.id = strconv.FormatInt(command.Id, 10)
+	}
+
+	switch command.Type {
+	case commandTypeDelete:
+		req.method = http.MethodDelete
+	case commandTypeCreate:
+		if command.Name == "" || len(command.Name) > maxNameLength {
+			return commandType, errors.New("name must have between 1 and " + strconv.Itoa(maxNameLength) + " characters")
+		}
+		req.method = http.MethodPost
+	default:
+		return commandType, errors.New("command type is not supported")
+	}
+
+	log(logrus.InfoLevel, ctx, req.user, "Command is received: "+commandType)
+	_, err = processNameRequest(ctx, scope, req)
+	return commandType, err
+}
+
+func getKnownCommandType(
+	commandType string,
+) string {
+	switch commandType {
+	case commandTypeDelete, commandTypeCreate:
+		return commandType
+	default:
+		return commandTypeUnknown
+	}
+}
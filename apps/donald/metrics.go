@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	dbErrors               metric.Int64Counter
+	dbQueryDuration        metric.Float64Histogram
+	rowsReturned           metric.Int64Histogram
+	schemaCacheHits        metric.Int64Counter
+	schemaCacheMisses      metric.Int64Counter
+	schemaCacheEvictions   metric.Int64Counter
+	postprocessingDuration metric.Float64Histogram
+	authFailures           metric.Int64Counter
+	messagingLag           metric.Float64Histogram
+	messagingProcessing    metric.Float64Histogram
+	seedRowsInserted       metric.Int64Counter
+	seedDuration           metric.Float64Histogram
+	transactionDuration    metric.Float64Histogram
+	transactionRollbacks   metric.Int64Counter
+	dbFaults               metric.Int64Counter
+	dbConnectionsUsage     metric.Int64ObservableGauge
+	dbConnectionsMax       metric.Int64ObservableGauge
+	dbConnectionsWaits     metric.Int64ObservableCounter
+	dbConnectionsWaitTime  metric.Float64ObservableCounter
+)
+
+func createInstruments() {
+	meter := otel.GetMeterProvider().Meter(appName)
+
+	var err error
+	dbErrors, err = meter.Int64Counter("db.errors")
+	if err != nil {
+		panic(err)
+	}
+
+	dbQueryDuration, err = meter.Float64Histogram("db.query.duration")
+	if err != nil {
+		panic(err)
+	}
+
+	rowsReturned, err = meter.Int64Histogram("rows.returned")
+	if err != nil {
+		panic(err)
+	}
+
+	schemaCacheHits, err = meter.Int64Counter("schema.cache.hits")
+	if err != nil {
+		panic(err)
+	}
+
+	schemaCacheMisses, err = meter.Int64Counter("schema.cache.misses")
+	if err != nil {
+		panic(err)
+	}
+
+	schemaCacheEvictions, err = meter.Int64Counter("schema.cache.evictions")
+	if err != nil {
+		panic(err)
+	}
+
+	postprocessingDuration, err = meter.Float64Histogram("postprocessing.duration")
+	if err != nil {
+		panic(err)
+	}
+
+	authFailures, err = meter.Int64Counter("auth.failures")
+	if err != nil {
+		panic(err)
+	}
+
+	messagingLag, err = meter.Float64Histogram("messaging.lag")
+	if err != nil {
+		panic(err)
+	}
+
+	messagingProcessing, err = meter.Float64Histogram("messaging.process.duration")
+	if err != nil {
+		panic(err)
+	}
+
+	seedRowsInserted, err = meter.Int64Counter("seed.rows")
+	if err != nil {
+		panic(err)
+	}
+
+	seedDuration, err = meter.Float64Histogram("seed.duration")
+	if err != nil {
+		panic(err)
+	}
+
+	transactionDuration, err = meter.Float64Histogram("db.transaction.duration")
+	if err != nil {
+		panic(err)
+	}
+
+	transactionRollbacks, err = meter.Int64Counter("db.transaction.rollbacks")
+	if err != nil {
+		panic(err)
+	}
+
+	dbFaults, err = meter.Int64Counter("db.faults.injected")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Creates the instruments which observe the connection pool of the database.
+// They are created after the database is connected, so that their callbacks
+// do not race with the assignment of the connection.
+func createDatabaseInstruments() {
+	meter := otel.GetMeterProvider().Meter(appName)
+
+	var err error
+	dbConnectionsUsage, err = meter.Int64ObservableGauge(
+		"db.client.connections.usage",
+		metric.WithInt64Callback(observeDbConnectionsUsage),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	dbConnectionsMax, err = meter.Int64ObservableGauge(
+		"db.client.connections.max",
+		metric.WithInt64Callback(observeDbConnectionsMax),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	dbConnectionsWaits, err = meter.Int64ObservableCounter(
+		"db.client.connections.waits",
+		metric.WithInt64Callback(observeDbConnectionsWaits),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	dbConnectionsWaitTime, err = meter.Float64ObservableCounter(
+		"db.client.connections.wait_time",
+		metric.WithFloat64Callback(observeDbConnectionsWaitTime),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Observes the open connections of the pool by whether they are used or
+// idle.
+func observeDbConnectionsUsage(
+	ctx context.Context,
+	observer metric.Int64Observer,
+) error {
+	stats := db.Stats()
+	observer.Observe(int64(stats.InUse), metric.WithAttributes(
+		attribute.String("pool.name", mysqlDatabase),
+		attribute.String("state", "used"),
+	))
+	observer.Observe(int64(stats.Idle), metric.WithAttributes(
+		attribute.String("pool.name", mysqlDatabase),
+		attribute.String("state", "idle"),
+	))
+	return nil
+}
+
+// Observes the maximum number of open connections (0 means unlimited).
+func observeDbConnectionsMax(
+	ctx context.Context,
+	observer metric.Int64Observer,
+) error {
+	observer.Observe(int64(db.Stats().MaxOpenConnections), metric.WithAttributes(attribute.String("pool.name", mysqlDatabase)))
+	return nil
+}
+
+// Observes the total number of waits for a free connection.
+func observeDbConnectionsWaits(
+	ctx context.Context,
+	observer metric.Int64Observer,
+) error {
+	observer.Observe(db.Stats().WaitCount, metric.WithAttributes(attribute.String("pool.name", mysqlDatabase)))
+	return nil
+}
+
+// Observes the total time in milliseconds which was spent waiting for a free
+// connection.
+func observeDbConnectionsWaitTime(
+	ctx context.Context,
+	observer metric.Float64Observer,
+) error {
+	waitTime := float64(db.Stats().WaitDuration) / float64(time.Millisecond)
+	observer.Observe(waitTime, metric.WithAttributes(attribute.String("pool.name", mysqlDatabase)))
+	return nil
+}
+
+// Records the time between the publishing and the processing of the command
+// in milliseconds.
+func recordCommandLag(
+	ctx context.Context,
+	subject string,
+	commandType string,
+	publishedAt int64,
+) {
+	if publishedAt <= 0 {
+		return
+	}
+	lag := float64(time.Now().UnixMilli() - publishedAt)
+	messagingLag.Record(ctx, lag,
+		metric.WithAttributes(
+			attribute.String("messaging.destination.name", subject),
+			attribute.String("command.type", commandType),
+		),
+	)
+}
+
+func recordCommandProcessing(
+	ctx context.Context,
+	subject string,
+	commandType string,
+	success bool,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+	messagingProcessing.Record(ctx, elapsedTime,
+		metric.WithAttributes(
+			attribute.String("messaging.destination.name", subject),
+			attribute.String("command.type", commandType),
+			attribute.Bool("success", success),
+		),
+	)
+}
+
+// Records the duration of the transaction and the reason of its rollback.
+func recordTransaction(
+	ctx context.Context,
+	name string,
+	committed bool,
+	rollbackReason string,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+	attrs := getCommonDbMetricAttributes()
+	attrs = append(attrs, attribute.String("db.transaction.name", name))
+	attrs = append(attrs, attribute.Bool("committed", committed))
+	transactionDuration.Record(ctx, elapsedTime, metric.WithAttributes(attrs...))
+
+	if !committed {
+		attrs = getCommonDbMetricAttributes()
+		attrs = append(attrs, attribute.String("db.transaction.name", name))
+		attrs = append(attrs, attribute.String("reason", rollbackReason))
+		transactionRollbacks.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+}
+
+func recordSeed(
+	ctx context.Context,
+	trigger string,
+	rows int,
+	success bool,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+	seedRowsInserted.Add(ctx, int64(rows), metric.WithAttributes(attribute.String("seed.trigger", trigger)))
+	seedDuration.Record(ctx, elapsedTime,
+		metric.WithAttributes(
+			attribute.String("seed.trigger", trigger),
+			attribute.Bool("success", success),
+		),
+	)
+}
+
+func recordAuthFailure(
+	ctx context.Context,
+	method string,
+	reason string,
+) {
+	authFailures.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("auth.method", method),
+			attribute.String("reason", reason),
+		),
+	)
+}
+
+func recordDbError(
+	ctx context.Context,
+	dbOperation string,
+	kind string,
+) {
+	attrs := getCommonDbMetricAttributes()
+	attrs = append(attrs, attribute.String("db.operation", dbOperation))
+	attrs = append(attrs, attribute.String("kind", kind))
+	dbErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+func recordDbQueryDuration(
+	ctx context.Context,
+	dbOperation string,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+	attrs := getCommonDbMetricAttributes()
+	attrs = append(attrs, attribute.String("db.operation", dbOperation))
+	dbQueryDuration.Record(ctx, elapsedTime, metric.WithAttributes(attrs...))
+}
+
+func recordRowsReturned(
+	ctx context.Context,
+	dbOperation string,
+	rows int,
+) {
+	attrs := getCommonDbMetricAttributes()
+	attrs = append(attrs, attribute.String("db.operation", dbOperation))
+	rowsReturned.Record(ctx, int64(rows), metric.WithAttributes(attrs...))
+}
+
+func recordPostprocessingDuration(
+	ctx context.Context,
+	cacheHit bool,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+	postprocessingDuration.Record(ctx, elapsedTime, metric.WithAttributes(attribute.Bool("cache.hit", cacheHit)))
+}
+
+func getCommonDbMetricAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "mysql"),
+		attribute.String("db.name", mysqlDatabase),
+		attribute.String("db.sql.table", mysqlTable),
+	}
+}
+
+// Classifies database errors into a low cardinality kind which can be
+// used as a metric attribute.
+func getDbErrorKind(
+	err error,
+) string {
+	if isConnectionLost(err) {
+		return "connection_lost"
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1146:
+			return "table_not_found"
+		case 1205:
+			return "lock_wait_timeout"
+		case 1213:
+			return "deadlock"
+		}
+		return "mysql_error"
+	}
+	return "unknown"
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	migrationsTable    = "schema_migrations"
+	migrationsLockName = "donald_migrations"
+
+	defaultDatabaseLockTimeout = 60 * time.Second
+)
+
+var (
+	migrationsLockTimeout time.Duration
+
+	errDatabaseLocked = errors.New("database lock is held by another instance")
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Versioned change of the database schema. The migrations are forward-only
+// and every version is applied exactly once.
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	checksum   string
+}
+
+// Entry of the migrations history table.
+type appliedMigration struct {
+	version   int
+	checksum  string
+	appliedAt time.Time
+}
+
+// Runs the given migrate command instead of serving requests:
+// status lists the migrations, dry-run prints the pending statements and up
+// applies them.
+func runMigrateCommand(
+	command string,
+) error {
+	ctx := withoutFaults(context.Background())
+	switch command {
+	case "status":
+		return printMigrationsStatus(ctx)
+	case "dry-run":
+		return printPendingMigrations(ctx)
+	case "up":
+		return migrateDatabase(ctx)
+	default:
+		return errors.New("unknown migrate command " + command + " (status, dry-run, up)")
+	}
+}
+
+// Tells whether the migrate command only inspects the database without
+// changing it.
+func isReadOnlyMigrateCommand(
+	command string,
+) bool {
+	return command == "status" || command == "dry-run"
+}
+
+// Applies the pending migrations. The replicas of donald synchronize over a
+// named MySQL lock so that only one of them applies them. The migrations are
+// kept free from the injected faults.
+func migrateDatabase(
+	ctx context.Context,
+) error {
+	ctx = withoutFaults(ctx)
+	ctx, span := otel.GetTracerProvider().Tracer(appName).Start(ctx, "migrate",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		setSpanError(span, err.Error())
+		return err
+	}
+
+	// Use a single connection since the lock belongs to the session
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		setSpanError(span, err.Error())
+		return err
+	}
+	defer conn.Close()
+
+	err = acquireDatabaseLock(ctx, conn, migrationsLockName, migrationsLockTimeout)
+	if err != nil {
+		setSpanError(span, err.Error())
+		return err
+	}
+	defer releaseDatabaseLock(ctx, conn, migrationsLockName)
+
+	applied, err := getAppliedMigrations(ctx, conn, false)
+	if err != nil {
+		setSpanError(span, err.Error())
+		return err
+	}
+
+	// Applied migrations must not be changed afterwards
+	err = checkAppliedMigrations(migrations, applied)
+	if err != nil {
+		setSpanError(span, err.Error())
+		return err
+	}
+
+	pending := getPendingMigrations(migrations, applied)
+	span.SetAttributes(
+		attribute.Int("migrations.applied", len(applied)),
+		attribute.Int("migrations.pending", len(pending)),
+	)
+
+	for _, m := range pending {
+		err = applyMigration(ctx, conn, m)
+		if err != nil {
+			setSpanError(span, err.Error())
+			return err
+		}
+	}
+
+	logrus.Info("Database is migrated, " + strconv.Itoa(len(pending)) + " migrations are applied.")
+	return nil
+}
+
+func applyMigration(
+	ctx context.Context,
+	conn *sql.Conn,
+	m *migration,
+) error {
+	dbSpanAttrs := getCommonDbSpanAttributes()
+	dbSpanAttrs = append(dbSpanAttrs,
+		attribute.Int("migration.version", m.version),
+		attribute.String("migration.name", m.name),
+		attribute.String("db.statement", strings.Join(m.statements, ";\n")),
+	)
+	ctx, span := otel.GetTracerProvider().Tracer(appName).Start(ctx, "migration "+m.name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbSpanAttrs...),
+	)
+	defer span.End()
+
+	// DDL statements are committed implicitly by MySQL, hence a failed
+	// migration has to be fixed with a following one.
+	for _, statement := range m.statements {
+		_, err := conn.ExecContext(ctx, statement)
+		if err != nil {
+			setSpanError(span, err.Error())
+			return errors.New("migration " + m.name + " failed: " + err.Error())
+		}
+	}
+
+	_, err := conn.ExecContext(ctx,
+		"INSERT INTO "+migrationsTable+" (version, name, checksum) VALUES (?, ?, ?)",
+		m.version, m.name, m.checksum,
+	)
+	if err != nil {
+		setSpanError(span, err.Error())
+		return err
+	}
+
+	logrus.Info("Migration " + m.name + " is applied.")
+	return nil
+}
+
+// Acquires the named MySQL lock for the session of the connection. The lock
+// is shared by all of the replicas which use the same database. MySQL waits
+// for the lock in whole seconds, so the timeout is rounded up.
+func acquireDatabaseLock(
+	ctx context.Context,
+	conn *sql.Conn,
+	lockName string,
+	timeout time.Duration,
+) error {
+	span := trace.SpanFromContext(ctx)
+
+	if timeout <= 0 {
+		timeout = defaultDatabaseLockTimeout
+	}
+	timeoutInSeconds := int(math.Ceil(timeout.Seconds()))
+
+	lockStartTime := time.Now()
+	var acquired sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, timeoutInSeconds).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+
+	span.AddEvent("db.lock", trace.WithAttributes(
+		attribute.String("lock.name", lockName),
+		attribute.Bool("acquired", acquired.Int64 == 1),
+		attribute.Int64("wait.ms", time.Since(lockStartTime).Milliseconds()),
+	))
+	if acquired.Int64 != 1 {
+		return errDatabaseLocked
+	}
+	return nil
+}
+
+func releaseDatabaseLock(
+	ctx context.Context,
+	conn *sql.Conn,
+	lockName string,
+) {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+	if err != nil {
+		logrus.Warn("Lock " + lockName + " could not be released: " + err.Error())
+	}
+}
+
+// Reads the history of the applied migrations. The history table is created
+// if it does not exist yet. When the database is only inspected, a missing
+// table means that nothing is applied.
+func getAppliedMigrations(
+	ctx context.Context,
+	conn *sql.Conn,
+	readOnly bool,
+) (
+	map[int]*appliedMigration,
+	error,
+) {
+	if readOnly {
+		var tables int
+		err := conn.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ?",
+			mysqlDatabase, migrationsTable,
+		).Scan(&tables)
+		if err != nil {
+			return nil, err
+		}
+		if tables == 0 {
+			return map[int]*appliedMigration{}, nil
+		}
+	} else {
+		_, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+migrationsTable+" (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM "+mysqlDatabase+"."+migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]*appliedMigration{}
+	for rows.Next() {
+		var version int
+		var checksum string
+		var appliedAt []byte
+		err = rows.Scan(&version, &checksum, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		a := &appliedMigration{
+			version:  version,
+			checksum: checksum,
+		}
+		a.appliedAt, _ = time.Parse("2006-01-02 15:04:05", string(appliedAt))
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+func checkAppliedMigrations(
+	migrations []*migration,
+	applied map[int]*appliedMigration,
+) error {
+	for _, m := range migrations {
+		if a, ok := applied[m.version]; ok && a.checksum != m.checksum {
+			return errors.New("migration " + m.name + " was modified after it was applied at " + a.appliedAt.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func getPendingMigrations(
+	migrations []*migration,
+	applied map[int]*appliedMigration,
+) []*migration {
+	pending := []*migration{}
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+func printMigrationsStatus(
+	ctx context.Context,
+) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	applied, err := getAppliedMigrations(ctx, conn, true)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		a, ok := applied[m.version]
+		switch {
+		case !ok:
+			fmt.Println(m.name + "\tpending")
+		case a.checksum != m.checksum:
+			fmt.Println(m.name + "\tmodified after it was applied at " + a.appliedAt.Format(time.RFC3339))
+		default:
+			fmt.Println(m.name + "\tapplied at " + a.appliedAt.Format(time.RFC3339))
+		}
+	}
+
+	// Modified migrations fail the command
+	return checkAppliedMigrations(migrations, applied)
+}
+
+func printPendingMigrations(
+	ctx context.Context,
+) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	applied, err := getAppliedMigrations(ctx, conn, true)
+	if err != nil {
+		return err
+	}
+
+	// The pending migrations would not be applied over modified ones
+	err = checkAppliedMigrations(migrations, applied)
+	if err != nil {
+		return err
+	}
+
+	pending := getPendingMigrations(migrations, applied)
+	if len(pending) == 0 {
+		fmt.Println("No pending migrations.")
+		return nil
+	}
+	for _, m := range pending {
+		fmt.Println("-- " + m.name)
+		for _, statement := range m.statements {
+			fmt.Println(statement + ";")
+		}
+	}
+	return nil
+}
+
+// Loads the embedded migrations ordered by their versions. The files are
+// named <version>_<name>.sql and ${table} is replaced with the configured
+// table.
+func loadMigrations() (
+	[]*migration,
+	error,
+) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []*migration{}
+	versions := map[int]string{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		versionPart, _, found := strings.Cut(fileName, "_")
+		version, err := strconv.Atoi(versionPart)
+		if !found || err != nil || !strings.HasSuffix(fileName, ".sql") {
+			return nil, errors.New("migration " + fileName + " is not named <version>_<name>.sql")
+		}
+		if other, ok := versions[version]; ok {
+			return nil, errors.New("migrations " + other + " and " + fileName + " have the same version")
+		}
+		versions[version] = fileName
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", fileName))
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(content)
+
+		migrations = append(migrations, &migration{
+			version:    version,
+			name:       strings.TrimSuffix(fileName, ".sql"),
+			statements: splitStatements(strings.ReplaceAll(string(content), "${table}", mysqlTable)),
+			checksum:   hex.EncodeToString(checksum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// Splits the script into its statements. Comment lines are dropped and the
+// statements are separated by semicolons at the end of a line.
+func splitStatements(
+	script string,
+) []string {
+	statements := []string{}
+	var sb strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		sb.WriteString(line + "\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(sb.String()), ";"))
+			sb.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(sb.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
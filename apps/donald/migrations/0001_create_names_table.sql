@@ -0,0 +1,6 @@
+-- Table of the names which joe lists and deletes. Existing tables which were
+-- created before the migrations are kept as they are.
+CREATE TABLE IF NOT EXISTS ${table} (
+  id INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(50) NOT NULL
+);
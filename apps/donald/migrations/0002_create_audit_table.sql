@@ -0,0 +1,10 @@
+-- Audit entries which are written within the same transaction as the
+-- changes of the names.
+CREATE TABLE IF NOT EXISTS ${table}_audit (
+  id INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  operation VARCHAR(16) NOT NULL,
+  username VARCHAR(64) NOT NULL,
+  name_id INT NULL,
+  rows_affected INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -2,12 +2,12 @@ package main
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/metric/global"
 	"go.opentelemetry.io/otel/propagation"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -15,6 +15,10 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
+var (
+	metricExporters string
+)
+
 func newTraceProvider(
 	ctx context.Context,
 ) *sdktrace.TracerProvider {
@@ -73,16 +77,33 @@ func shutdownTraceProvider(
 func newMetricProvider(
 	ctx context.Context,
 ) *sdkmetric.MeterProvider {
-	var exp sdkmetric.Exporter
-	var err error
+	opts := []sdkmetric.Option{
+		sdkmetric.WithView(loadMetricViews()...),
+	}
 
-	exp, err = otlpmetricgrpc.New(ctx)
-	if err != nil {
-		panic(err)
+	// Metrics are pushed over OTLP, scraped by Prometheus or both
+	if metricExporters == "" {
+		metricExporters = "otlp"
+	}
+	for _, exporter := range strings.Split(metricExporters, ",") {
+		switch strings.TrimSpace(exporter) {
+		case "otlp":
+			exp, err := otlpmetricgrpc.New(ctx)
+			if err != nil {
+				panic(err)
+			}
+			opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp)))
+		case "prometheus":
+			opts = append(opts, sdkmetric.WithReader(newPrometheusReader()))
+		case "":
+		default:
+			panic("unknown metric exporter: " + exporter)
+		}
 	}
 
-	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp)))
-	global.SetMeterProvider(mp)
+	mp := sdkmetric.NewMeterProvider(opts...)
+	otel.SetMeterProvider(mp)
+	startRuntimeInstrumentation(mp)
 	return mp
 }
 
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+var (
+	prometheusPort string
+)
+
+// Creates the reader which is scraped by Prometheus and serves it on
+// /metrics of its own port. The resource of the metric provider is exposed
+// as target_info.
+func newPrometheusReader() sdkmetric.Reader {
+	registry := prometheus.NewRegistry()
+	exp, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", ":"+prometheusPort)
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil {
+			logrus.Error("Prometheus endpoint stopped: " + err.Error())
+		}
+	}()
+
+	logrus.Info("Prometheus endpoint is listening on port " + prometheusPort + ".")
+	return exp
+}
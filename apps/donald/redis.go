@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	redisServer        string
+	redisPort          string
+	useFakeRedisServer bool
+)
+
+const (
+	redisMaxIdleConnections = 8
+)
+
+// Schema cache backend which talks the Redis protocol (RESP) to an external
+// cache server. The connections are kept in a small pool and re-established
+// after failures.
+type redisSchemaCache struct {
+	address string
+	ttl     time.Duration
+	timeout time.Duration
+	idle    chan *redisConnection
+}
+
+type redisConnection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRedisSchemaCache(
+	address string,
+	ttl time.Duration,
+) *redisSchemaCache {
+	return &redisSchemaCache{
+		address: address,
+		ttl:     ttl,
+		timeout: time.Second,
+		idle:    make(chan *redisConnection, redisMaxIdleConnections),
+	}
+}
+
+func (c *redisSchemaCache) get(
+	ctx context.Context,
+	key string,
+) (
+	string,
+	bool,
+	error,
+) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return "", false, err
+	}
+	if reply == nil {
+		return "", false, nil
+	}
+	schema, _ := reply.(string)
+	return schema, true, nil
+}
+
+func (c *redisSchemaCache) set(
+	ctx context.Context,
+	key string,
+	schema string,
+) error {
+	args := []string{key, schema}
+	if c.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10))
+	}
+	_, err := c.do(ctx, "SET", args...)
+	return err
+}
+
+func (c *redisSchemaCache) invalidate(
+	ctx context.Context,
+	key string,
+) error {
+	_, err := c.do(ctx, "DEL", key)
+	return err
+}
+
+// Executes a single command and tracks it with a client span when cache
+// spans are enabled.
+func (c *redisSchemaCache) do(
+	ctx context.Context,
+	command string,
+	args ...string,
+) (
+	interface{},
+	error,
+) {
+	if !considerCacheSpans {
+		return c.roundTrip(ctx, command, args...)
+	}
+
+	attrs := getCommonCacheSpanAttributes()
+	attrs = append(attrs, attribute.String("db.operation", command))
+	attrs = append(attrs, attribute.String("db.statement", command+" "+args[0]))
+
+	_, cacheSpan := getRequestScope(ctx).startClientSpan(ctx, command, attrs...)
+	defer cacheSpan.End()
+
+	reply, err := c.roundTrip(ctx, command, args...)
+	if err != nil {
+		cacheSpan.RecordError(err)
+		setSpanError(cacheSpan, err.Error())
+	}
+	return reply, err
+}
+
+// Sends the command over a connection of the pool and waits for its reply
+// no longer than the timeout or the deadline of the context allows.
+func (c *redisSchemaCache) roundTrip(
+	ctx context.Context,
+	command string,
+	args ...string,
+) (
+	interface{},
+	error,
+) {
+	rc, err := c.acquireConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	rc.conn.SetDeadline(deadline)
+
+	_, err = rc.conn.Write(encodeRespCommand(command, args...))
+	if err != nil {
+		rc.conn.Close()
+		return nil, err
+	}
+
+	reply, err := readRespReply(rc.reader)
+	if err != nil {
+		// Server side errors leave the connection in a consistent state
+		var respErr respError
+		if errors.As(err, &respErr) {
+			c.releaseConnection(rc)
+		} else {
+			rc.conn.Close()
+		}
+		return nil, err
+	}
+	c.releaseConnection(rc)
+	return reply, nil
+}
+
+// Takes an idle connection from the pool or dials a new one.
+func (c *redisSchemaCache) acquireConnection(
+	ctx context.Context,
+) (
+	*redisConnection,
+	error,
+) {
+	select {
+	case rc := <-c.idle:
+		return rc, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	return &redisConnection{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}, nil
+}
+
+// Puts the connection back into the pool or closes it when the pool is full.
+func (c *redisSchemaCache) releaseConnection(
+	rc *redisConnection,
+) {
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+func getCommonCacheSpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("net.peer.name", redisServer),
+		attribute.String("net.peer.port", redisPort),
+		attribute.String("net.transport", "IP.TCP"),
+		attribute.Int("db.redis.database_index", 0),
+	}
+}
+
+// Error reply which is returned by the server.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+func encodeRespCommand(
+	command string,
+	args ...string,
+) []byte {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)+1) + "\r\n")
+	for _, arg := range append([]string{command}, args...) {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	return []byte(b.String())
+}
+
+// Reads a single RESP reply. Simple strings and bulk strings are returned as
+// string, integers as int64, arrays as []interface{} and nil bulk strings as
+// nil.
+func readRespReply(
+	reader *bufio.Reader,
+) (
+	interface{},
+	error,
+) {
+	line, err := readRespLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		_, err = io.ReadFull(reader, buf)
+		if err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		elems := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			elem, err := readRespReply(reader)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		return elems, nil
+	default:
+		return nil, errors.New("unknown redis reply type: " + string(line[0]))
+	}
+}
+
+func readRespLine(
+	reader *bufio.Reader,
+) (
+	string,
+	error,
+) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
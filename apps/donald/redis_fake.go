@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// In-memory server which understands the subset of the Redis protocol that
+// the schema cache uses (PING, GET, SET with PX, DEL). It allows to run and
+// verify the Redis backend without an actual Redis deployment.
+type fakeRedisServer struct {
+	mutex    sync.Mutex
+	values   map[string]string
+	expiries map[string]time.Time
+	listener net.Listener
+}
+
+func startFakeRedisServer(
+	address string,
+) (
+	*fakeRedisServer,
+	error,
+) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fakeRedisServer{
+		values:   make(map[string]string),
+		expiries: make(map[string]time.Time),
+		listener: listener,
+	}
+	go s.serve()
+
+	logrus.Info("Fake Redis server is listening on " + listener.Addr().String() + ".")
+	return s, nil
+}
+
+func (s *fakeRedisServer) close() error {
+	return s.listener.Close()
+}
+
+func (s *fakeRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *fakeRedisServer) handleConnection(
+	conn net.Conn,
+) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		request, err := readRespReply(reader)
+		if err != nil {
+			return
+		}
+
+		args, ok := request.([]interface{})
+		if !ok || len(args) == 0 {
+			conn.Write([]byte("-ERR invalid request\r\n"))
+			continue
+		}
+
+		strArgs := make([]string, 0, len(args))
+		for _, arg := range args {
+			strArg, _ := arg.(string)
+			strArgs = append(strArgs, strArg)
+		}
+
+		_, err = conn.Write(s.execute(strArgs))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedisServer) execute(
+	args []string,
+) []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return []byte("+PONG\r\n")
+	case "GET":
+		if len(args) != 2 {
+			return []byte("-ERR wrong number of arguments for 'get' command\r\n")
+		}
+		value, ok := s.lookup(args[1])
+		if !ok {
+			return []byte("$-1\r\n")
+		}
+		return []byte("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")
+	case "SET":
+		if len(args) != 3 && len(args) != 5 {
+			return []byte("-ERR syntax error\r\n")
+		}
+		s.values[args[1]] = args[2]
+		delete(s.expiries, args[1])
+		if len(args) == 5 {
+			if strings.ToUpper(args[3]) != "PX" {
+				return []byte("-ERR syntax error\r\n")
+			}
+			ttl, err := strconv.ParseInt(args[4], 10, 64)
+			if err != nil {
+				return []byte("-ERR value is not an integer or out of range\r\n")
+			}
+			s.expiries[args[1]] = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+		return []byte("+OK\r\n")
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				delete(s.values, key)
+				delete(s.expiries, key)
+				deleted++
+			}
+		}
+		return []byte(":" + strconv.Itoa(deleted) + "\r\n")
+	default:
+		return []byte("-ERR unknown command '" + args[0] + "'\r\n")
+	}
+}
+
+func (s *fakeRedisServer) lookup(
+	key string,
+) (
+	string,
+	bool,
+) {
+	value, ok := s.values[key]
+	if !ok {
+		return "", false
+	}
+	if expiresAt, ok := s.expiries[key]; ok && time.Now().After(expiresAt) {
+		delete(s.values, key)
+		delete(s.expiries, key)
+		return "", false
+	}
+	return value, true
+}
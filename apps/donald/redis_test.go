@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestRedisSchemaCache(
+	t *testing.T,
+	ttl time.Duration,
+) *redisSchemaCache {
+	t.Helper()
+
+	server, err := startFakeRedisServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.close() })
+
+	return newRedisSchemaCache(server.listener.Addr().String(), ttl)
+}
+
+func TestRedisSchemaCacheSetAndGet(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisSchemaCache(t, time.Minute)
+
+	err := c.set(ctx, "names:GET:user", "schema-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, ok, err := c.get(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || schema != "schema-1" {
+		t.Fatalf("expected schema-1, got %q (found: %v)", schema, ok)
+	}
+
+	// Values are overwritten
+	err = c.set(ctx, "names:GET:user", "schema-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, ok, err = c.get(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || schema != "schema-2" {
+		t.Fatalf("expected schema-2, got %q (found: %v)", schema, ok)
+	}
+}
+
+func TestRedisSchemaCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisSchemaCache(t, 50*time.Millisecond)
+
+	err := c.set(ctx, "names:GET:user", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok, err := c.get(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected the entry before its expiry")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, ok, err = c.get(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected the entry to be expired")
+	}
+}
+
+func TestRedisSchemaCacheMiss(t *testing.T) {
+	ctx := context.Background()
+	c := newTestRedisSchemaCache(t, time.Minute)
+
+	schema, ok, err := c.get(ctx, "names:GET:unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok || schema != "" {
+		t.Fatalf("expected a miss, got %q", schema)
+	}
+
+	// Invalidated entries are missed as well
+	err = c.set(ctx, "names:GET:user", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.invalidate(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok, err = c.get(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected the invalidated entry to be missed")
+	}
+}
+
+func TestRedisSchemaCacheSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+
+	previousConsiderCacheSpans := considerCacheSpans
+	considerCacheSpans = true
+	t.Cleanup(func() { considerCacheSpans = previousConsiderCacheSpans })
+
+	ctx := context.Background()
+	c := newTestRedisSchemaCache(t, time.Minute)
+
+	err := c.set(ctx, "names:GET:user", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = c.get(ctx, "names:GET:user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, operation := range []string{"SET", "GET"} {
+		span := spans[i]
+		if span.Name() != operation {
+			t.Errorf("expected span %s, got %s", operation, span.Name())
+		}
+		if span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("expected a client span for %s, got %s", operation, span.SpanKind())
+		}
+
+		attrs := attribute.NewSet(span.Attributes()...)
+		if value, _ := attrs.Value("db.system"); value.AsString() != "redis" {
+			t.Errorf("expected db.system=redis for %s, got %q", operation, value.AsString())
+		}
+		if value, _ := attrs.Value("db.operation"); value.AsString() != operation {
+			t.Errorf("expected db.operation=%s, got %q", operation, value.AsString())
+		}
+		if value, _ := attrs.Value("db.statement"); value.AsString() != operation+" names:GET:user" {
+			t.Errorf("expected the statement of %s, got %q", operation, value.AsString())
+		}
+	}
+}
+
+func TestRedisSchemaCacheServerDown(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+
+	previousCache := cache
+	previousSchemaCacheMisses := schemaCacheMisses
+	t.Cleanup(func() {
+		cache = previousCache
+		schemaCacheMisses = previousSchemaCacheMisses
+	})
+	schemaCacheMisses, _ = noop.NewMeterProvider().Meter(appName).Int64Counter("schema.cache.misses")
+
+	// Nothing listens on the address anymore
+	server, err := startFakeRedisServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := server.listener.Addr().String()
+	server.close()
+	cache = newRedisSchemaCache(address, time.Minute)
+
+	ctx, span := otel.GetTracerProvider().Tracer(appName).Start(context.Background(), "postprocessing")
+	_, _, err = cache.get(ctx, "names:GET:user")
+	if err == nil {
+		t.Fatal("expected an error while the server is down")
+	}
+
+	// The lookup degrades to a miss and calculates the schema from scratch
+	cacheHit := performSchemaLookup(ctx, &nameRequest{method: "GET", user: "user"})
+	span.End()
+	if cacheHit {
+		t.Fatal("expected a miss while the server is down")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 1 || events[0].Name != "schema.cache.error" {
+		t.Fatalf("expected the schema.cache.error event, got %v", events)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const deadlockGap = 200 * time.Millisecond
+
+var (
+	lockHoldDuration time.Duration
+	lockWaitTimeout  time.Duration
+)
+
+// Causes real issues on the side of the database before the query of the
+// request is performed. The statements wait for each other within MySQL
+// instead of being faked by donald.
+func performDatabaseScenarios(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) error {
+	if req.lockContention {
+		err := performLockContention(ctx, scope, req)
+		if err != nil {
+			return err
+		}
+	}
+	if req.deadlock {
+		return performDeadlock(ctx, scope, req)
+	}
+	return nil
+}
+
+// Locks the first row of the table and holds the lock for a while. The
+// requests of all replicas lock the same row, hence they queue up behind each
+// other and fail when they wait longer than the lock wait timeout.
+func performLockContention(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) error {
+	log(logrus.InfoLevel, ctx, req.user, "Locking first name...")
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Fail after the configured time instead of the default 50 seconds
+	_, err = conn.ExecContext(ctx, "SET SESSION innodb_lock_wait_timeout = "+strconv.Itoa(getLockWaitTimeoutInSeconds()))
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SET SESSION innodb_lock_wait_timeout = DEFAULT")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = executeScenarioStatement(ctx, scope, req.user, tx, "SELECT id FROM "+mysqlTable+" ORDER BY id LIMIT 1 FOR UPDATE")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Hold the lock so that the other requests have to wait for it
+	trace.SpanFromContext(ctx).AddEvent("db.lock.hold", trace.WithAttributes(
+		attribute.Int64("duration.ms", lockHoldDuration.Milliseconds()),
+	))
+	select {
+	case <-time.After(lockHoldDuration):
+	case <-ctx.Done():
+		tx.Rollback()
+		return ctx.Err()
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	log(logrus.InfoLevel, ctx, req.user, "Lock of first name is released.")
+	return nil
+}
+
+// Runs two transactions concurrently which lock the first and the last row of
+// the table in opposite orders. MySQL detects the deadlock and rolls one of
+// them back.
+func performDeadlock(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) error {
+	var firstId, lastId sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT MIN(id), MAX(id) FROM "+mysqlTable).Scan(&firstId, &lastId)
+	if err != nil {
+		return err
+	}
+	if !firstId.Valid || firstId.Int64 == lastId.Int64 {
+		log(logrus.WarnLevel, ctx, req.user, "Deadlock requires at least two names.")
+		return nil
+	}
+
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, ids := range [][]int64{{firstId.Int64, lastId.Int64}, {lastId.Int64, firstId.Int64}} {
+		wg.Add(1)
+		go func(i int, ids []int64) {
+			defer wg.Done()
+			errs[i] = performDeadlockTransaction(ctx, scope, req.user, i+1, ids)
+		}(i, ids)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func performDeadlockTransaction(
+	ctx context.Context,
+	scope *requestScope,
+	user string,
+	number int,
+	ids []int64,
+) error {
+	return runInTransaction(ctx, scope, user, "deadlock "+strconv.Itoa(number), func(ctx context.Context, tx *sql.Tx) error {
+		for i, id := range ids {
+			// Let the other transaction lock its first row in the meantime
+			if i > 0 {
+				time.Sleep(deadlockGap)
+			}
+
+			err := executeScenarioStatement(ctx, scope, user, tx, "SELECT id FROM "+mysqlTable+" WHERE id = ? FOR UPDATE", id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func executeScenarioStatement(
+	ctx context.Context,
+	scope *requestScope,
+	user string,
+	tx *sql.Tx,
+	dbStatement string,
+	dbArgs ...interface{},
+) error {
+	dbOperation := strings.Fields(dbStatement)[0]
+
+	var dbSpan trace.Span
+	if considerDatabaseSpans {
+		dbSpanAttrs := getCommonDbSpanAttributes()
+		dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
+		dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.statement", dbStatement))
+
+		ctx, dbSpan = scope.startClientSpan(
+			ctx,
+			dbOperation+" "+mysqlDatabase+"."+mysqlTable,
+			dbSpanAttrs...,
+		)
+		defer dbSpan.End()
+	}
+
+	// Start timer
+	queryStartTime := time.Now()
+	defer recordDbQueryDuration(ctx, dbOperation, queryStartTime)
+
+	rows, err := tx.QueryContext(ctx, dbStatement, dbArgs...)
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+	}
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		recordDbError(ctx, dbOperation, getDbErrorKind(err))
+		if dbSpan != nil {
+			setSpanError(dbSpan, err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// MySQL accepts the lock wait timeout in whole seconds only.
+func getLockWaitTimeoutInSeconds() int {
+	seconds := int(lockWaitTimeout / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
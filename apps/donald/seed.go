@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errorCodeInvalidSeed = "invalid_seed"
+
+	seedLockName     = "donald_seed"
+	maxSeedRows      = 10000
+	maxSeedBatchSize = 10000
+)
+
+var (
+	seedOnStartup bool
+	seedRows      int
+	seedValue     int64
+	seedBatchSize int
+
+	seedFirstNames = []string{
+		"ada", "alan", "barbara", "claude", "dennis", "donald", "edsger", "frances",
+		"grace", "guido", "hedy", "james", "joan", "ken", "linus", "margaret",
+		"niklaus", "radia", "rob", "tim",
+	}
+	seedLastNames = []string{
+		"allen", "backus", "berners-lee", "dijkstra", "hamilton", "hopper", "kernighan", "knuth",
+		"lamarr", "liskov", "lovelace", "perlman", "pike", "ritchie", "shannon", "thompson",
+		"torvalds", "turing", "van rossum", "wirth",
+	}
+)
+
+// Outcome of a seeding run.
+type seedResponse struct {
+	Seed       int64 `json:"seed"`
+	Rows       int   `json:"rows"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Generates the synthetic names. The same seed always yields the same names
+// in the same order.
+type nameGenerator struct {
+	random *rand.Rand
+}
+
+func newNameGenerator(
+	seed int64,
+) *nameGenerator {
+	return &nameGenerator{
+		random: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (g *nameGenerator) next() string {
+	return seedFirstNames[g.random.Intn(len(seedFirstNames))] + " " +
+		seedLastNames[g.random.Intn(len(seedLastNames))] + " " +
+		strconv.Itoa(g.random.Intn(10000))
+}
+
+// Fills the table up to the configured number of rows on startup. The
+// generator continues after the existing rows so that restarts of donald do
+// not grow the table any further.
+func seedDatabaseOnStartup() {
+	if !seedOnStartup || seedRows <= 0 {
+		return
+	}
+
+	ctx, span := otel.GetTracerProvider().Tracer(appName).Start(context.Background(), "seed",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("seed.trigger", "startup"),
+			attribute.Int64("seed.value", seedValue),
+		),
+	)
+	defer span.End()
+
+	// Start timer
+	seedStartTime := time.Now()
+
+	inserted, err := seedDatabaseUpTo(ctx, seedValue, seedRows)
+	recordSeed(ctx, "startup", inserted, err == nil, seedStartTime)
+	if err != nil {
+		logrus.Warn("Database could not be seeded: " + err.Error())
+		setSpanError(span, err.Error())
+		return
+	}
+	logrus.Info("Database is seeded with " + strconv.Itoa(inserted) + " names.")
+}
+
+func seedDatabaseUpTo(
+	ctx context.Context,
+	seed int64,
+	rows int,
+) (
+	int,
+	error,
+) {
+	// Replicas seed one after another, the seeding is kept free from the
+	// injected faults
+	ctx = withoutFaults(ctx)
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	err = acquireDatabaseLock(ctx, conn, seedLockName, migrationsLockTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer releaseDatabaseLock(ctx, conn, seedLockName)
+
+	var existing int
+	err = conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+mysqlTable).Scan(&existing)
+	if err != nil {
+		return 0, err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("seed.existing_rows", existing))
+	if existing >= rows {
+		return 0, nil
+	}
+
+	// Skip the names which were already inserted by earlier runs
+	generator := newNameGenerator(seed)
+	for i := 0; i < existing; i++ {
+		generator.next()
+	}
+	return insertSeedNames(ctx, conn, generator, rows-existing)
+}
+
+// Inserts the names in batches of multi-row inserts. Every batch is tracked
+// with a span of its own.
+func insertSeedNames(
+	ctx context.Context,
+	conn *sql.Conn,
+	generator *nameGenerator,
+	rows int,
+) (
+	int,
+	error,
+) {
+	batchSize := seedBatchSize
+	if batchSize <= 0 || batchSize > maxSeedBatchSize {
+		batchSize = 500
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.Int("seed.rows", rows),
+		attribute.Int("seed.batch_size", batchSize),
+	)
+
+	inserted := 0
+	for inserted < rows {
+		size := batchSize
+		if rows-inserted < size {
+			size = rows - inserted
+		}
+
+		dbArgs := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			dbArgs = append(dbArgs, generator.next())
+		}
+		dbStatement := "INSERT INTO " + mysqlTable + " (name) VALUES " + strings.TrimSuffix(strings.Repeat("(?),", size), ",")
+
+		err := insertSeedBatch(ctx, conn, dbStatement, dbArgs)
+		if err != nil {
+			span.SetAttributes(attribute.Int("seed.inserted_rows", inserted))
+			return inserted, err
+		}
+		inserted += size
+	}
+
+	span.SetAttributes(attribute.Int("seed.inserted_rows", inserted))
+	return inserted, nil
+}
+
+func insertSeedBatch(
+	ctx context.Context,
+	conn *sql.Conn,
+	dbStatement string,
+	dbArgs []interface{},
+) error {
+	dbOperation := "INSERT"
+	dbSpanAttrs := getCommonDbSpanAttributes()
+	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
+	dbSpanAttrs = append(dbSpanAttrs, attribute.Int("db.rows", len(dbArgs)))
+
+	ctx, dbSpan := otel.GetTracerProvider().Tracer(appName).Start(ctx, dbOperation+" "+mysqlDatabase+"."+mysqlTable,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dbSpanAttrs...),
+	)
+	defer dbSpan.End()
+
+	// Start timer
+	queryStartTime := time.Now()
+	defer recordDbQueryDuration(ctx, dbOperation, queryStartTime)
+
+	_, err := conn.ExecContext(ctx, dbStatement, dbArgs...)
+	if err != nil {
+		recordDbError(ctx, dbOperation, getDbErrorKind(err))
+		setSpanError(dbSpan, err.Error())
+		return err
+	}
+	return nil
+}
+
+// Appends the given number of names on demand. Without a seed, the
+// configured one is used.
+func seedHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	scope *requestScope,
+) {
+	user := getUser(r)
+	log(logrus.InfoLevel, r.Context(), user, "Seed handler is triggered")
+
+	query := r.URL.Query()
+	rows, err := strconv.Atoi(query.Get("rows"))
+	if err != nil || rows <= 0 || rows > maxSeedRows {
+		createErrorResponse(&w, http.StatusBadRequest, errorCodeInvalidSeed, "Rows must be between 1 and "+strconv.Itoa(maxSeedRows)+".", scope)
+		return
+	}
+	seed := seedValue
+	if query.Get("seed") != "" {
+		seed, err = strconv.ParseInt(query.Get("seed"), 10, 64)
+		if err != nil {
+			createErrorResponse(&w, http.StatusBadRequest, errorCodeInvalidSeed, "Seed is not a number.", scope)
+			return
+		}
+	}
+
+	ctx, span := scope.startInternalSpan(r.Context(), "seed",
+		attribute.String("seed.trigger", "admin"),
+		attribute.Int64("seed.value", seed),
+	)
+	defer span.End()
+
+	// Start timer
+	seedStartTime := time.Now()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		setSpanError(span, err.Error())
+		createQueryErrorResponse(&w, err, scope)
+		return
+	}
+	defer conn.Close()
+
+	inserted, err := insertSeedNames(ctx, conn, newNameGenerator(seed), rows)
+	recordSeed(ctx, "admin", inserted, err == nil, seedStartTime)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, "Database could not be seeded: "+err.Error())
+		setSpanError(span, err.Error())
+		createQueryErrorResponse(&w, err, scope)
+		return
+	}
+	log(logrus.InfoLevel, ctx, user, "Database is seeded with "+strconv.Itoa(inserted)+" names.")
+
+	body, _ := json.Marshal(seedResponse{
+		Seed:       seed,
+		Rows:       inserted,
+		DurationMs: time.Since(seedStartTime).Milliseconds(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	createHttpResponse(&w, http.StatusOK, body)
+}
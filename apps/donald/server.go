@@ -5,191 +5,281 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// Transport independent request to donald. It is built from both the HTTP
+// and the gRPC requests so that they are processed the same way.
+type nameRequest struct {
+	method                       string
+	user                         string
+	id                           string
+	name                         string
+	databaseConnectionError      bool
+	tableDoesNotExistError       bool
+	schemaNotFoundInCacheWarning bool
+	slowQuery                    bool
+	lockContention               bool
+	deadlock                     bool
+}
+
+// Outcome of the database query.
+type nameResult struct {
+	names        []string
+	rowsAffected int64
+	lastInsertId int64
+	count        int64
+}
+
 func handler(
 	w http.ResponseWriter,
 	r *http.Request,
+	scope *requestScope,
 ) {
 
-	// Get server span
-	parentSpan := trace.SpanFromContext(r.Context())
-	defer parentSpan.End()
-
 	log(logrus.InfoLevel, r.Context(), getUser(r), "Handler is triggered")
 
-	// Perform database query
-	err := performQuery(w, r, &parentSpan)
+	// Validate path parameters
+	if id, ok := scope.pathParams["id"]; ok {
+		if _, err := strconv.Atoi(id); err != nil {
+			createErrorResponse(&w, http.StatusBadRequest, errorCodeInvalidId, "Provided id is not a number.", scope)
+			return
+		}
+	}
+
+	_, err := processNameRequest(r.Context(), scope, newNameRequest(r, scope))
 	if err != nil {
+		createQueryErrorResponse(&w, err, scope)
 		return
 	}
 
-	performPostprocessing(r, &parentSpan)
-	createHttpResponse(&w, http.StatusOK, []byte("Success"), &parentSpan)
+	createHttpResponse(&w, http.StatusOK, []byte("Success"))
 }
 
-func performQuery(
-	w http.ResponseWriter,
+func newNameRequest(
 	r *http.Request,
-	parentSpan *trace.Span,
-) error {
-	if considerDatabaseSpans {
-		err := performQueryWithDbSpan(w, r, parentSpan)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := performQueryWithoutDbSpan(w, r, parentSpan)
-		if err != nil {
-			return err
-		}
+	scope *requestScope,
+) *nameRequest {
+	query := r.URL.Query()
+	return &nameRequest{
+		method:                       r.Method,
+		user:                         getUser(r),
+		id:                           scope.pathParams["id"],
+		databaseConnectionError:      query.Get("databaseConnectionError") == "true",
+		tableDoesNotExistError:       query.Get("tableDoesNotExistError") == "true",
+		schemaNotFoundInCacheWarning: query.Get("schemaNotFoundInCacheWarning") == "true",
+		slowQuery:                    query.Get("slowQuery") == "true",
+		lockContention:               query.Get("lockContention") == "true",
+		deadlock:                     query.Get("deadlock") == "true",
 	}
-	return nil
 }
 
-func performQueryWithDbSpan(
-	w http.ResponseWriter,
-	r *http.Request,
-	parentSpan *trace.Span,
-) error {
+// Performs the database query and the postprocessing of the request.
+func processNameRequest(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) (
+	*nameResult,
+	error,
+) {
+	if isConcurrentRequest(req) {
+		return processNameRequestConcurrently(ctx, scope, req)
+	}
 
-	// Build query
-	dbOperation, dbStatement, err := createDbQuery(r)
+	// Perform database query
+	result, err := performQuery(ctx, scope, req)
 	if err != nil {
-		createHttpResponse(&w, http.StatusMethodNotAllowed, []byte("Method not allowed"), parentSpan)
-		return err
+		return nil, err
 	}
 
-	ctx, dbSpan := (*parentSpan).TracerProvider().
-		Tracer(appName).
-		Start(
-			r.Context(),
-			dbOperation+" "+mysqlDatabase+"."+mysqlTable,
-			trace.WithSpanKind(trace.SpanKindClient),
-		)
-	defer dbSpan.End()
+	performPostprocessing(ctx, scope, req)
+	return result, nil
+}
 
-	// Set additional span attributes
-	dbSpanAttrs := getCommonDbSpanAttributes()
-	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
-	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.statement", dbStatement))
+func performQuery(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) (
+	*nameResult,
+	error,
+) {
+	// Drop the connections of the request within the driver
+	if req.databaseConnectionError {
+		ctx = withFaultTrigger(ctx, faultKindDrop)
+	}
 
-	// Perform query
-	err = executeDbQuery(ctx, r, dbStatement)
+	// Simulate issues within the database
+	err := performDatabaseScenarios(ctx, scope, req)
 	if err != nil {
-		// Add status code
-		dbSpanAttrs = append(dbSpanAttrs, attribute.String("otel.status_code", "ERROR"))
-		dbSpan.SetAttributes(dbSpanAttrs...)
-
-		createHttpResponse(&w, http.StatusInternalServerError, []byte(err.Error()), parentSpan)
-		return err
+		return nil, err
 	}
 
-	// Create database connection error
-	databaseConnectionError := r.URL.Query().Get("databaseConnectionError")
-	if databaseConnectionError == "true" {
-		msg := "Connection to database is lost."
-		log(logrus.ErrorLevel, ctx, getUser(r), msg)
-
-		// Add status code
-		dbSpanAttrs = append(dbSpanAttrs, attribute.String("otel.status_code", "ERROR"))
-		dbSpan.SetAttributes(dbSpanAttrs...)
+	// Writes are performed together with their audit entries
+	if req.method != http.MethodGet {
+		return performWriteTransaction(ctx, scope, req)
+	}
+	return performStatement(ctx, scope, req, db)
+}
 
-		createHttpResponse(&w, http.StatusInternalServerError, []byte(msg), parentSpan)
-		return errors.New("database connection lost")
+func performStatement(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+	executor dbExecutor,
+) (
+	*nameResult,
+	error,
+) {
+	if considerDatabaseSpans {
+		return performQueryWithDbSpan(ctx, scope, req, executor)
 	}
-	dbSpan.SetAttributes(dbSpanAttrs...)
-	return nil
+	return performQueryWithoutDbSpan(ctx, req, executor)
 }
 
-func performQueryWithoutDbSpan(
-	w http.ResponseWriter,
-	r *http.Request,
-	parentSpan *trace.Span,
-) error {
+func performQueryWithDbSpan(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+	executor dbExecutor,
+) (
+	*nameResult,
+	error,
+) {
+
 	// Build query
-	_, dbStatement, err := createDbQuery(r)
-	if err != nil {
-		createHttpResponse(&w, http.StatusMethodNotAllowed, []byte("Method not allowed"), parentSpan)
-		return err
-	}
+	dbOperation, dbStatement, dbArgs := createDbQuery(ctx, req)
+
+	// Set additional span attributes
+	dbSpanAttrs := getCommonDbSpanAttributes()
+	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
+	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.statement", dbStatement))
+
+	ctx, dbSpan := scope.startClientSpan(
+		ctx,
+		dbOperation+" "+mysqlDatabase+"."+mysqlTable,
+		dbSpanAttrs...,
+	)
+	defer dbSpan.End()
 
 	// Perform query
-	err = executeDbQuery(r.Context(), r, dbStatement)
+	result, err := executeDbQuery(ctx, executor, req.user, dbOperation, dbStatement, dbArgs...)
 	if err != nil {
-		createHttpResponse(&w, http.StatusInternalServerError, []byte(err.Error()), parentSpan)
-		return err
+		setSpanError(dbSpan, err.Error())
+		return nil, err
 	}
+	return result, nil
+}
 
-	// Parse query parameters
-	databaseConnectionError := r.URL.Query().Get("databaseConnectionError")
-	if databaseConnectionError == "true" {
-		msg := "Connection to database is lost."
-		log(logrus.ErrorLevel, r.Context(), getUser(r), msg)
-		createHttpResponse(&w, http.StatusInternalServerError, []byte(msg), parentSpan)
-		return errors.New("database connection lost")
-	}
-	return nil
+func performQueryWithoutDbSpan(
+	ctx context.Context,
+	req *nameRequest,
+	executor dbExecutor,
+) (
+	*nameResult,
+	error,
+) {
+	// Build query
+	dbOperation, dbStatement, dbArgs := createDbQuery(ctx, req)
+
+	// Perform query
+	return executeDbQuery(ctx, executor, req.user, dbOperation, dbStatement, dbArgs...)
 }
 
 func createDbQuery(
-	r *http.Request,
+	ctx context.Context,
+	req *nameRequest,
 ) (
 	string,
 	string,
-	error,
+	[]interface{},
 ) {
-	log(logrus.InfoLevel, r.Context(), getUser(r), "Building query...")
+	log(logrus.InfoLevel, ctx, req.user, "Building query...")
 
-	var dbOperation string
+	dbOperation := getDbOperation(req.method)
 	var dbStatement string
+	var dbArgs []interface{}
 
-	switch r.Method {
+	// Methods are already checked by the router
+	switch req.method {
 	case http.MethodGet:
-		dbOperation = "SELECT"
-
 		// Create table does not exist error
-		tableDoesNotExistError := r.URL.Query().Get("tableDoesNotExistError")
-		if tableDoesNotExistError == "true" {
+		if req.tableDoesNotExistError {
 			dbStatement = dbOperation + " name FROM " + "faketable"
+		} else if req.slowQuery {
+			// Scan the whole table even for a single id
+			dbStatement = dbOperation + " name FROM " + mysqlTable + " IGNORE INDEX (PRIMARY)"
 		} else {
 			dbStatement = dbOperation + " name FROM " + mysqlTable
 		}
-		return dbOperation, dbStatement, nil
 	case http.MethodDelete:
-		dbOperation = "DELETE"
 		dbStatement = dbOperation + " FROM " + mysqlTable
-	default:
-		log(logrus.ErrorLevel, r.Context(), getUser(r), "Method is not allowed.")
-		return "", "", errors.New("method not allowed")
+	case http.MethodPost:
+		dbStatement = dbOperation + " INTO " + mysqlTable + " (name) VALUES (?)"
+		dbArgs = append(dbArgs, req.name)
+	}
+
+	// Restrict the query to a single row
+	if req.id != "" && dbOperation != "INSERT" {
+		dbStatement += " WHERE id = ?"
+		dbArgs = append(dbArgs, req.id)
 	}
 
-	log(logrus.InfoLevel, r.Context(), getUser(r), "Query is built.")
-	return dbOperation, dbStatement, nil
+	// Sort all of the scanned rows
+	if req.slowQuery && dbOperation == "SELECT" && !req.tableDoesNotExistError {
+		dbStatement += " ORDER BY MD5(CONCAT(name, RAND()))"
+	}
+
+	log(logrus.InfoLevel, ctx, req.user, "Query is built.")
+	return dbOperation, dbStatement, dbArgs
+}
+
+func getDbOperation(
+	method string,
+) string {
+	switch method {
+	case http.MethodDelete:
+		return "DELETE"
+	case http.MethodPost:
+		return "INSERT"
+	default:
+		return "SELECT"
+	}
 }
 
 func executeDbQuery(
 	ctx context.Context,
-	r *http.Request,
+	executor dbExecutor,
+	user string,
+	dbOperation string,
 	dbStatement string,
-) error {
+	dbArgs ...interface{},
+) (
+	*nameResult,
+	error,
+) {
 
-	log(logrus.InfoLevel, ctx, getUser(r), "Executing query...")
+	log(logrus.InfoLevel, ctx, user, "Executing query...")
 
-	user := getUser(r)
-	switch r.Method {
-	case http.MethodGet:
+	// Start timer
+	queryStartTime := time.Now()
+	defer recordDbQueryDuration(ctx, dbOperation, queryStartTime)
+
+	result := &nameResult{}
+	switch dbOperation {
+	case "SELECT":
 		// Perform a query
-		rows, err := db.Query(dbStatement)
+		rows, err := executor.QueryContext(ctx, dbStatement, dbArgs...)
 		if err != nil {
 			log(logrus.ErrorLevel, ctx, user, err.Error())
-			return err
+			recordDbError(ctx, dbOperation, getDbErrorKind(err))
+			return nil, err
 		}
 		defer rows.Close()
 
@@ -200,44 +290,46 @@ func executeDbQuery(
 			err = rows.Scan(&name)
 			if err != nil {
 				log(logrus.ErrorLevel, ctx, user, err.Error())
-				return err
+				recordDbError(ctx, dbOperation, "scan")
+				return nil, err
 			}
 			names = append(names, name)
 		}
+		recordRowsReturned(ctx, dbOperation, len(names))
 
 		_, err = json.Marshal(names)
 		if err != nil {
 			log(logrus.ErrorLevel, ctx, user, err.Error())
-			return err
+			return nil, err
 		}
-	case http.MethodDelete:
-		_, err := db.Exec(dbStatement)
+		result.names = names
+	case "DELETE", "INSERT":
+		res, err := executor.ExecContext(ctx, dbStatement, dbArgs...)
 		if err != nil {
 			log(logrus.ErrorLevel, ctx, user, err.Error())
-			return err
+			recordDbError(ctx, dbOperation, getDbErrorKind(err))
+			return nil, err
 		}
+		result.rowsAffected, _ = res.RowsAffected()
+		result.lastInsertId, _ = res.LastInsertId()
 	default:
-		log(logrus.ErrorLevel, ctx, getUser(r), "Method is not allowed.")
-		return errors.New("method not allowed")
+		log(logrus.ErrorLevel, ctx, user, "Database operation is not supported.")
+		return nil, errors.New("database operation not supported")
 	}
 
-	log(logrus.InfoLevel, ctx, getUser(r), "Query is executed.")
-	return nil
+	log(logrus.InfoLevel, ctx, user, "Query is executed.")
+	return result, nil
 }
 
+// Writes the response. The HTTP status code and the span status of the
+// server span are set by the otelhttp handler accordingly.
 func createHttpResponse(
 	w *http.ResponseWriter,
 	statusCode int,
 	body []byte,
-	serverSpan *trace.Span,
 ) {
 	(*w).WriteHeader(statusCode)
 	(*w).Write(body)
-
-	attrs := []attribute.KeyValue{
-		semconv.HTTPStatusCode(statusCode),
-	}
-	(*serverSpan).SetAttributes(attrs...)
 }
 
 func getCommonDbSpanAttributes() []attribute.KeyValue {
@@ -253,49 +345,67 @@ func getCommonDbSpanAttributes() []attribute.KeyValue {
 }
 
 func performPostprocessing(
-	r *http.Request,
-	parentSpan *trace.Span,
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
 ) {
 
+	// Start timer
+	postprocessingStartTime := time.Now()
+
 	if considerPostprocessingSpans {
-		ctx, processingSpan := (*parentSpan).TracerProvider().
-			Tracer(appName).
-			Start(
-				r.Context(),
-				"postprocessing",
-				trace.WithSpanKind(trace.SpanKindInternal),
-			)
+		ctx, processingSpan := scope.startInternalSpan(ctx, "postprocessing")
 		defer processingSpan.End()
 
-		produceSchemaNotFoundInCacheWarning(ctx, r)
+		cacheHit := performSchemaLookup(ctx, req)
+		performEnrichment(ctx, req.user)
+		recordPostprocessingDuration(ctx, cacheHit, postprocessingStartTime)
 	} else {
-		produceSchemaNotFoundInCacheWarning(r.Context(), r)
+		cacheHit := performSchemaLookup(ctx, req)
+		performEnrichment(ctx, req.user)
+		recordPostprocessingDuration(ctx, cacheHit, postprocessingStartTime)
 	}
 }
 
-func produceSchemaNotFoundInCacheWarning(
+func performSchemaLookup(
 	ctx context.Context,
-	r *http.Request,
-) {
-	log(logrus.InfoLevel, ctx, getUser(r), "Postprocessing...")
-	schemaNotFoundInCacheWarning := r.URL.Query().Get("schemaNotFoundInCacheWarning")
-	if schemaNotFoundInCacheWarning == "true" {
-		user := getUser(r)
-		log(logrus.WarnLevel, ctx, user, "Processing schema not found in cache. Calculating from scratch.")
-		time.Sleep(time.Millisecond * 500)
-	} else {
-		time.Sleep(time.Millisecond * 10)
+	req *nameRequest,
+) bool {
+	log(logrus.InfoLevel, ctx, req.user, "Postprocessing...")
+
+	user := req.user
+	key := getSchemaKey(req.method, user)
+	span := trace.SpanFromContext(ctx)
+
+	// Drop the cached schema on demand to simulate a cache miss
+	if req.schemaNotFoundInCacheWarning {
+		err := cache.invalidate(ctx, key)
+		if err != nil {
+			log(logrus.WarnLevel, ctx, user, "Processing schema could not be invalidated in cache: "+err.Error())
+		}
 	}
-	log(logrus.InfoLevel, r.Context(), getUser(r), "Postprocessing is complete.")
-}
 
-func getUser(
-	r *http.Request,
-) string {
+	_, cacheHit, err := cache.get(ctx, key)
+	if err != nil {
+		log(logrus.WarnLevel, ctx, user, "Processing schema cache is not reachable. Calculating from scratch.")
+		span.AddEvent("schema.cache.error", trace.WithAttributes(attribute.String("schema.key", key)))
+		schemaCacheMisses.Add(ctx, 1)
+		computeSchema(key)
+		return false
+	}
 
-	user := r.Header.Get("X-User-ID")
-	if user == "" {
-		user = "_anonymous_"
+	if cacheHit {
+		span.AddEvent("schema.cache.hit", trace.WithAttributes(attribute.String("schema.key", key)))
+		schemaCacheHits.Add(ctx, 1)
+	} else {
+		log(logrus.WarnLevel, ctx, user, "Processing schema not found in cache. Calculating from scratch.")
+		span.AddEvent("schema.cache.miss", trace.WithAttributes(attribute.String("schema.key", key)))
+		schemaCacheMisses.Add(ctx, 1)
+		err = cache.set(ctx, key, computeSchema(key))
+		if err != nil {
+			log(logrus.WarnLevel, ctx, user, "Processing schema could not be stored in cache: "+err.Error())
+		}
 	}
-	return user
+	log(logrus.InfoLevel, ctx, user, "Postprocessing is complete.")
+	return cacheHit
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Statements of a request are executed either directly on the database or
+// within a transaction.
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Performs the write of the request together with its audit entry. Both of
+// them are rolled back when one of them fails.
+func performWriteTransaction(
+	ctx context.Context,
+	scope *requestScope,
+	req *nameRequest,
+) (
+	*nameResult,
+	error,
+) {
+	var result *nameResult
+	err := runInTransaction(ctx, scope, req.user, getDbOperation(req.method), func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		result, err = performStatement(ctx, scope, req, tx)
+		if err != nil {
+			return err
+		}
+		return insertAuditEntry(ctx, scope, tx, req, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Runs the given function within a transaction. The transaction is committed
+// when the function succeeds and rolled back otherwise.
+func runInTransaction(
+	ctx context.Context,
+	scope *requestScope,
+	user string,
+	name string,
+	fn func(ctx context.Context, tx *sql.Tx) error,
+) error {
+	var span trace.Span
+	if considerDatabaseSpans {
+		ctx, span = scope.startInternalSpan(ctx, "transaction "+name,
+			attribute.String("db.transaction.name", name),
+		)
+		defer span.End()
+	}
+
+	// Start timer
+	transactionStartTime := time.Now()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		recordDbError(ctx, name, getDbErrorKind(err))
+		return err
+	}
+
+	err = fn(ctx, tx)
+	if err != nil {
+		reason := getRollbackReason(err)
+		log(logrus.WarnLevel, ctx, user, "Transaction is rolled back: "+reason)
+		trace.SpanFromContext(ctx).AddEvent("db.transaction.rollback", trace.WithAttributes(
+			attribute.String("db.transaction.name", name),
+			attribute.String("reason", reason),
+			attribute.String("error", err.Error()),
+		))
+		if span != nil {
+			setSpanError(span, err.Error())
+		}
+
+		// Cancelled transactions are already rolled back by database/sql
+		rollbackErr := finishTransaction(ctx, scope, "ROLLBACK", tx.Rollback)
+		if rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			log(logrus.ErrorLevel, ctx, user, "Transaction could not be rolled back: "+rollbackErr.Error())
+		}
+		recordTransaction(ctx, name, false, reason, transactionStartTime)
+		return err
+	}
+
+	err = finishTransaction(ctx, scope, "COMMIT", tx.Commit)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, "Transaction could not be committed: "+err.Error())
+		if span != nil {
+			setSpanError(span, err.Error())
+		}
+		recordTransaction(ctx, name, false, getRollbackReason(err), transactionStartTime)
+		return err
+	}
+
+	recordTransaction(ctx, name, true, "", transactionStartTime)
+	return nil
+}
+
+// Commits or rolls back the transaction within a database span.
+func finishTransaction(
+	ctx context.Context,
+	scope *requestScope,
+	dbOperation string,
+	finish func() error,
+) error {
+	if !considerDatabaseSpans {
+		return finish()
+	}
+
+	dbSpanAttrs := getCommonDbSpanAttributes()
+	dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
+	_, dbSpan := scope.startClientSpan(ctx, dbOperation+" "+mysqlDatabase, dbSpanAttrs...)
+	defer dbSpan.End()
+
+	err := finish()
+	if err != nil {
+		setSpanError(dbSpan, err.Error())
+	}
+	return err
+}
+
+func insertAuditEntry(
+	ctx context.Context,
+	scope *requestScope,
+	tx *sql.Tx,
+	req *nameRequest,
+	result *nameResult,
+) error {
+	dbOperation := "INSERT"
+	dbStatement := dbOperation + " INTO " + mysqlTable + "_audit (operation, username, name_id, rows_affected) VALUES (?, ?, ?, ?)"
+
+	var nameId sql.NullInt64
+	if req.method == http.MethodPost {
+		nameId = sql.NullInt64{Int64: result.lastInsertId, Valid: true}
+	} else if id, err := strconv.ParseInt(req.id, 10, 64); err == nil {
+		nameId = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	var dbSpan trace.Span
+	if considerDatabaseSpans {
+		dbSpanAttrs := getCommonDbSpanAttributes()
+		dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.operation", dbOperation))
+		dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.statement", dbStatement))
+		dbSpanAttrs = append(dbSpanAttrs, attribute.String("db.sql.table", mysqlTable+"_audit"))
+
+		ctx, dbSpan = scope.startClientSpan(
+			ctx,
+			dbOperation+" "+mysqlDatabase+"."+mysqlTable+"_audit",
+			dbSpanAttrs...,
+		)
+		defer dbSpan.End()
+	}
+
+	// Start timer
+	queryStartTime := time.Now()
+	defer recordDbQueryDuration(ctx, dbOperation, queryStartTime)
+
+	_, err := tx.ExecContext(ctx, dbStatement, getDbOperation(req.method), req.user, nameId, result.rowsAffected)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, req.user, err.Error())
+		recordDbError(ctx, dbOperation, getDbErrorKind(err))
+		if dbSpan != nil {
+			setSpanError(dbSpan, err.Error())
+		}
+		return err
+	}
+	return nil
+}
+
+// Classifies the error which caused the rollback into a low cardinality
+// reason.
+func getRollbackReason(
+	err error,
+) string {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	default:
+		return getDbErrorKind(err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	errorCodeNotFound         = "not_found"
+	errorCodeMethodNotAllowed = "method_not_allowed"
+	errorCodeMissingUser      = "missing_user"
+	errorCodeBranchFailed     = "branch_failed"
+)
+
+// Body of every failed response so that the callers can tell what went
+// wrong and in which trace.
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	TraceId string `json:"traceId,omitempty"`
+}
+
+func createErrorResponse(
+	w *http.ResponseWriter,
+	statusCode int,
+	code string,
+	message string,
+	scope *requestScope,
+) {
+	res := errorResponse{
+		Code:    code,
+		Message: message,
+		TraceId: scope.traceId(),
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		body = []byte(message)
+	}
+
+	(*w).Header().Set("Content-Type", "application/json")
+	createHttpResponse(w, statusCode, body)
+}
+
+// Writes the response. The HTTP status code and the span status of the
+// server span are set by the otelhttp handler accordingly.
+func createHttpResponse(
+	w *http.ResponseWriter,
+	statusCode int,
+	body []byte,
+) {
+	(*w).WriteHeader(statusCode)
+	(*w).Write(body)
+}
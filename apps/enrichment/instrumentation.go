@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestScopeKey struct{}
+
+// Instrumentation of a single request. The server span is owned by the
+// otelhttp handler which starts it, ends it exactly once and derives the HTTP
+// status code and the span status from the written response. Child spans are
+// started through the scope.
+type requestScope struct {
+	serverSpan trace.Span
+	tracer     trace.Tracer
+	route      string
+	pathParams map[string]string
+}
+
+func newRequestScope(
+	serverSpan trace.Span,
+) *requestScope {
+	return &requestScope{
+		serverSpan: serverSpan,
+		tracer:     otel.GetTracerProvider().Tracer(appName),
+		pathParams: map[string]string{},
+	}
+}
+
+func instrumentHandler(
+	operation string,
+	handler func(http.ResponseWriter, *http.Request, *requestScope),
+	opts ...otelhttp.Option,
+) http.Handler {
+	return otelhttp.NewHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := newRequestScope(trace.SpanFromContext(r.Context()))
+			scope.serverSpan.SetAttributes(
+				attribute.String("net.protocol.name", "http"),
+				attribute.String("net.protocol.version", strconv.Itoa(r.ProtoMajor)+"."+strconv.Itoa(r.ProtoMinor)),
+			)
+			ctx := context.WithValue(r.Context(), requestScopeKey{}, scope)
+			handler(w, r.WithContext(ctx), scope)
+		}),
+		operation,
+		opts...,
+	)
+}
+
+func (s *requestScope) startInternalSpan(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (
+	context.Context,
+	trace.Span,
+) {
+	return s.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (s *requestScope) traceId() string {
+	if !s.serverSpan.SpanContext().HasTraceID() {
+		return ""
+	}
+	return s.serverSpan.SpanContext().TraceID().String()
+}
+
+// Marks the span as failed with the given description.
+func setSpanError(
+	span trace.Span,
+	description string,
+) {
+	span.SetStatus(codes.Error, description)
+}
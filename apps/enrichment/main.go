@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	appName string
+	appPort string
+
+	branchLatency     time.Duration
+	slowBranch        string
+	slowBranchLatency time.Duration
+	failingBranch     string
+	failingBranchRate int
+
+	logLevel       string
+	logWithContext bool
+)
+
+func main() {
+
+	// Parse arguments and feature flags
+	parseFlags()
+
+	// Init logger
+	initLogger()
+
+	// Get context
+	ctx := context.Background()
+
+	// Create tracer provider
+	tp := newTraceProvider(ctx)
+	defer shutdownTraceProvider(ctx, tp)
+
+	// Create metric provider
+	mp := newMetricProvider(ctx)
+	defer shutdownMetricProvider(ctx, mp)
+
+	// Create custom instruments
+	createInstruments()
+
+	// Serve HTTP
+	router := newRouter()
+	router.handle(http.MethodGet, "/api/enrich", handler)
+	http.ListenAndServe(":"+appPort, router.handler())
+}
+
+func parseFlags() {
+	appName = os.Getenv("APP_NAME")
+	appPort = os.Getenv("APP_PORT")
+
+	branchLatencyInMs, _ := strconv.ParseInt(os.Getenv("BRANCH_LATENCY"), 10, 64)
+	branchLatency = time.Duration(branchLatencyInMs) * time.Millisecond
+	slowBranch = strings.ToLower(os.Getenv("SLOW_BRANCH"))
+	slowBranchLatencyInMs, _ := strconv.ParseInt(os.Getenv("SLOW_BRANCH_LATENCY"), 10, 64)
+	slowBranchLatency = time.Duration(slowBranchLatencyInMs) * time.Millisecond
+	failingBranch = strings.ToLower(os.Getenv("FAILING_BRANCH"))
+	failingBranchRate, _ = strconv.Atoi(os.Getenv("FAILING_BRANCH_RATE"))
+
+	metricExporters = os.Getenv("METRIC_EXPORTERS")
+	prometheusPort = os.Getenv("PROMETHEUS_PORT")
+
+	logLevel = os.Getenv("LOG_LEVEL")
+	logWithContext, _ = strconv.ParseBool(os.Getenv("LOG_WITH_CONTEXT"))
+}
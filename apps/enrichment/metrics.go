@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	branchDuration metric.Float64Histogram
+)
+
+func createInstruments() {
+	meter := otel.GetMeterProvider().Meter(appName)
+
+	var err error
+	branchDuration, err = meter.Float64Histogram("enrichment.branch.duration")
+	if err != nil {
+		panic(err)
+	}
+}
+
+func recordBranchDuration(
+	ctx context.Context,
+	branch string,
+	success bool,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+	branchDuration.Record(ctx, elapsedTime,
+		metric.WithAttributes(
+			attribute.String("enrichment.branch", branch),
+			attribute.Bool("success", success),
+		),
+	)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+var (
+	metricExporters string
+)
+
+func newTraceProvider(
+	ctx context.Context,
+) *sdktrace.TracerProvider {
+
+	var exp sdktrace.SpanExporter
+	var err error
+
+	exp, err = otlptracegrpc.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	// Ensure default SDK resources and the required service name are set
+	r, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(
+			semconv.SchemaURL,
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	// Create trace provider
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(r),
+	)
+
+	// Set global trace provider
+	otel.SetTracerProvider(tp)
+
+	// Set trace propagator
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		))
+
+	return tp
+}
+
+func shutdownTraceProvider(
+	ctx context.Context,
+	tp *sdktrace.TracerProvider,
+) {
+	// Do not make the application hang when it is shutdown.
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	if err := tp.Shutdown(ctx); err != nil {
+		panic(err)
+	}
+}
+
+func newMetricProvider(
+	ctx context.Context,
+) *sdkmetric.MeterProvider {
+	opts := []sdkmetric.Option{}
+
+	// Metrics are pushed over OTLP, scraped by Prometheus or both
+	if metricExporters == "" {
+		metricExporters = "otlp"
+	}
+	for _, exporter := range strings.Split(metricExporters, ",") {
+		switch strings.TrimSpace(exporter) {
+		case "otlp":
+			exp, err := otlpmetricgrpc.New(ctx)
+			if err != nil {
+				panic(err)
+			}
+			opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp)))
+		case "prometheus":
+			opts = append(opts, sdkmetric.WithReader(newPrometheusReader()))
+		case "":
+		default:
+			panic("unknown metric exporter: " + exporter)
+		}
+	}
+
+	mp := sdkmetric.NewMeterProvider(opts...)
+	otel.SetMeterProvider(mp)
+	return mp
+}
+
+func shutdownMetricProvider(
+	ctx context.Context,
+	mp *sdkmetric.MeterProvider,
+) {
+	// Do not make the application hang when it is shutdown.
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	if err := mp.Shutdown(ctx); err != nil {
+		panic(err)
+	}
+}
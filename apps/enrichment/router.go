@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+type routeHandler func(http.ResponseWriter, *http.Request, *requestScope)
+
+type route struct {
+	method   string
+	template string
+	segments []string
+	handler  routeHandler
+}
+
+// Dispatches the requests to the registered routes. The server spans are
+// named after the matched route template, unknown paths are answered with
+// 404 and known paths with unregistered methods with 405.
+type router struct {
+	routes []*route
+}
+
+func newRouter() *router {
+	return &router{}
+}
+
+func (rt *router) handle(
+	method string,
+	template string,
+	handler routeHandler,
+) {
+	rt.routes = append(rt.routes, &route{
+		method:   method,
+		template: template,
+		segments: splitPath(template),
+		handler:  handler,
+	})
+}
+
+func (rt *router) handler() http.Handler {
+	return instrumentHandler(
+		"router",
+		rt.serve,
+		otelhttp.WithSpanNameFormatter(rt.formatSpanName),
+	)
+}
+
+func (rt *router) serve(
+	w http.ResponseWriter,
+	r *http.Request,
+	scope *requestScope,
+) {
+	matched, pathParams, allowedMethods := rt.match(r.Method, r.URL.Path)
+	if matched == nil {
+		if len(allowedMethods) > 0 {
+			w.Header().Set("Allow", strings.Join(allowedMethods, ", "))
+			createErrorResponse(&w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed.", scope)
+			return
+		}
+		createErrorResponse(&w, http.StatusNotFound, errorCodeNotFound, "Route not found.", scope)
+		return
+	}
+
+	// Tag the server span and the server metrics with the route
+	scope.serverSpan.SetAttributes(semconv.HTTPRoute(matched.template))
+	labeler, _ := otelhttp.LabelerFromContext(r.Context())
+	labeler.Add(semconv.HTTPRoute(matched.template))
+
+	scope.route = matched.template
+	scope.pathParams = pathParams
+	matched.handler(w, r, scope)
+}
+
+func (rt *router) formatSpanName(
+	_ string,
+	r *http.Request,
+) string {
+	matched, _, _ := rt.match(r.Method, r.URL.Path)
+	if matched == nil {
+		return "HTTP " + r.Method
+	}
+	return matched.method + " " + matched.template
+}
+
+// Looks up the route for the given method and path. When only the path
+// matches, the methods which are registered for it are returned instead.
+func (rt *router) match(
+	method string,
+	path string,
+) (
+	*route,
+	map[string]string,
+	[]string,
+) {
+	segments := splitPath(path)
+	allowedMethods := []string{}
+	for _, candidate := range rt.routes {
+		pathParams, ok := matchSegments(candidate.segments, segments)
+		if !ok {
+			continue
+		}
+		if candidate.method == method {
+			return candidate, pathParams, nil
+		}
+		allowedMethods = append(allowedMethods, candidate.method)
+	}
+	return nil, nil, allowedMethods
+}
+
+func matchSegments(
+	templateSegments []string,
+	pathSegments []string,
+) (
+	map[string]string,
+	bool,
+) {
+	if len(templateSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	pathParams := map[string]string{}
+	for i, templateSegment := range templateSegments {
+		if strings.HasPrefix(templateSegment, "{") && strings.HasSuffix(templateSegment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			pathParams[templateSegment[1:len(templateSegment)-1]] = pathSegments[i]
+			continue
+		}
+		if templateSegment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return pathParams, true
+}
+
+func splitPath(
+	path string,
+) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
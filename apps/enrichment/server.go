@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Branches which are looked up concurrently for every request.
+var branches = []string{"profile", "geo", "risk"}
+
+var (
+	segments  = []string{"consumer", "business", "enterprise"}
+	regions   = []string{"eu-central", "eu-west", "us-east", "us-west", "ap-south"}
+	riskTiers = []string{"low", "medium", "high"}
+)
+
+// Attributes which are added to the names of a user.
+type enrichmentResponse struct {
+	User       string            `json:"user"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Outcome of a single branch.
+type branchResult struct {
+	branch string
+	value  string
+	err    error
+}
+
+func handler(
+	w http.ResponseWriter,
+	r *http.Request,
+	scope *requestScope,
+) {
+	user := r.Header.Get("X-User-ID")
+	log(logrus.InfoLevel, r.Context(), user, "Handler is triggered")
+
+	if user == "" {
+		createErrorResponse(&w, http.StatusBadRequest, errorCodeMissingUser, "User is not provided.", scope)
+		return
+	}
+	scope.serverSpan.SetAttributes(attribute.String("enduser.id", user))
+
+	// The branches can be made slow or failing per request as well
+	query := r.URL.Query()
+	slow := slowBranch
+	if query.Has("slowBranch") {
+		slow = strings.ToLower(query.Get("slowBranch"))
+	}
+	failing := failingBranch
+	failingRate := failingBranchRate
+	if query.Has("failingBranch") {
+		failing = strings.ToLower(query.Get("failingBranch"))
+		failingRate = 100
+	}
+
+	results := performBranches(r.Context(), scope, user, slow, failing, failingRate)
+
+	res := enrichmentResponse{
+		User:       user,
+		Attributes: map[string]string{},
+	}
+	failedBranches := []string{}
+	for _, result := range results {
+		if result.err != nil {
+			failedBranches = append(failedBranches, result.branch)
+			continue
+		}
+		res.Attributes[result.branch] = result.value
+	}
+
+	if len(failedBranches) > 0 {
+		sort.Strings(failedBranches)
+		msg := "Enrichment failed for branches: " + strings.Join(failedBranches, ",") + "."
+		log(logrus.ErrorLevel, r.Context(), user, msg)
+		createErrorResponse(&w, http.StatusInternalServerError, errorCodeBranchFailed, msg, scope)
+		return
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		createErrorResponse(&w, http.StatusInternalServerError, errorCodeBranchFailed, err.Error(), scope)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	createHttpResponse(&w, http.StatusOK, body)
+}
+
+// Performs all of the branches concurrently and waits for every one of them.
+// Each branch has its own span so that the slow or the failing one can be
+// spotted within the trace.
+func performBranches(
+	ctx context.Context,
+	scope *requestScope,
+	user string,
+	slow string,
+	failing string,
+	failingRate int,
+) []*branchResult {
+	results := make([]*branchResult, len(branches))
+
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func(i int, branch string) {
+			defer wg.Done()
+			results[i] = performBranch(ctx, scope, user, branch, branch == slow, branch == failing && rand.Intn(100) < failingRate)
+		}(i, branch)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func performBranch(
+	ctx context.Context,
+	scope *requestScope,
+	user string,
+	branch string,
+	isSlow bool,
+	isFailing bool,
+) *branchResult {
+	ctx, span := scope.startInternalSpan(ctx, "enrich "+branch,
+		attribute.String("enrichment.branch", branch),
+	)
+	defer span.End()
+
+	// Start timer
+	branchStartTime := time.Now()
+
+	log(logrus.InfoLevel, ctx, user, "Enriching "+branch+"...")
+
+	// Simulate the lookup
+	latency := time.Duration(0)
+	if branchLatency > 0 {
+		latency = time.Duration(rand.Int63n(int64(branchLatency)))
+	}
+	if isSlow {
+		latency += slowBranchLatency
+		span.SetAttributes(attribute.Bool("enrichment.slow", true))
+	}
+	time.Sleep(latency)
+
+	result := &branchResult{branch: branch}
+	if isFailing {
+		result.err = errors.New("lookup of " + branch + " failed")
+		log(logrus.ErrorLevel, ctx, user, "Enrichment of "+branch+" failed.")
+		span.RecordError(result.err)
+		setSpanError(span, result.err.Error())
+		recordBranchDuration(ctx, branch, false, branchStartTime)
+		return result
+	}
+
+	result.value = lookupValue(branch, user)
+	span.AddEvent("enrichment.result", trace.WithAttributes(
+		attribute.String("value", result.value),
+	))
+	log(logrus.InfoLevel, ctx, user, "Enrichment of "+branch+" is complete.")
+	recordBranchDuration(ctx, branch, true, branchStartTime)
+	return result
+}
+
+// Derives a stable value from the user so that the same user is always
+// enriched the same way.
+func lookupValue(
+	branch string,
+	user string,
+) string {
+	h := fnv.New32a()
+	h.Write([]byte(user))
+	index := int(h.Sum32())
+
+	switch branch {
+	case "profile":
+		return segments[index%len(segments)]
+	case "geo":
+		return regions[index%len(regions)]
+	case "risk":
+		return riskTiers[index%len(riskTiers)] + ":" + strconv.Itoa(index%100)
+	default:
+		return ""
+	}
+}
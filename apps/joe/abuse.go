@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errorCodeUserBlocked = "user_blocked"
+	errorCodeRateLimited = "rate_limited"
+
+	maxTrackedUsers = 10000
+
+	defaultAbuseBlockDuration = 10 * time.Minute
+)
+
+var (
+	rateLimitPerSecond float64
+	rateLimitBurst     int
+	blockedUsers       string
+
+	abuseDetectionWindow      time.Duration
+	abuseDetectionMinRequests int
+	abuseDetectionThreshold   float64
+	abuseAutoBlock            bool
+	abuseBlockDuration        time.Duration
+
+	userBlockList     *blockList
+	userRateLimiter   *rateLimiter
+	userAbuseDetector *abuseDetector
+)
+
+func createAbuseProtection() {
+	userBlockList = newBlockList(blockedUsers)
+	userRateLimiter = newRateLimiter(rateLimitPerSecond, rateLimitBurst)
+	userAbuseDetector = newAbuseDetector(abuseDetectionWindow, abuseDetectionMinRequests, abuseDetectionThreshold)
+}
+
+// Users which are not allowed to call joe at all. The configured users are
+// blocked for good, the added ones until their block expires.
+type blockList struct {
+	mutex    sync.RWMutex
+	users    map[string]bool
+	expiries map[string]time.Time
+}
+
+func newBlockList(
+	users string,
+) *blockList {
+	l := &blockList{
+		users:    map[string]bool{},
+		expiries: map[string]time.Time{},
+	}
+	for _, user := range strings.Split(users, ",") {
+		user = strings.TrimSpace(user)
+		if user != "" {
+			l.users[user] = true
+		}
+	}
+	return l
+}
+
+func (l *blockList) contains(
+	user string,
+) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.users[user] {
+		return true
+	}
+	expiresAt, ok := l.expiries[user]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (l *blockList) add(
+	user string,
+	duration time.Duration,
+) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if len(l.expiries) >= maxTrackedUsers {
+		l.removeExpiredBlocks(now)
+	}
+	l.expiries[user] = now.Add(duration)
+}
+
+func (l *blockList) removeExpiredBlocks(
+	now time.Time,
+) {
+	for user, expiresAt := range l.expiries {
+		if !now.Before(expiresAt) {
+			delete(l.expiries, user)
+		}
+	}
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// Token bucket rate limiter per user. Every user may burst up to the bucket
+// size and is refilled with the configured rate.
+type rateLimiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(
+	rate float64,
+	burst int,
+) *rateLimiter {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// Takes a token for the user. If there is none left, the duration until the
+// next token becomes available is returned.
+func (l *rateLimiter) allow(
+	user string,
+) (
+	bool,
+	time.Duration,
+) {
+	// Rate limiting is disabled
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[user]
+	if !ok {
+		if len(l.buckets) >= maxTrackedUsers {
+			l.removeFullBuckets(now)
+		}
+		bucket = &tokenBucket{
+			tokens:     l.burst,
+			lastRefill: now,
+		}
+		l.buckets[user] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.lastRefill).Seconds()*l.rate)
+	bucket.lastRefill = now
+
+	if bucket.tokens < 1 {
+		retryAfter := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, retryAfter
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// Drops the buckets which would be full anyway to limit the memory usage.
+func (l *rateLimiter) removeFullBuckets(
+	now time.Time,
+) {
+	for user, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.lastRefill).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, user)
+		}
+	}
+}
+
+type userActivity struct {
+	windowStart  time.Time
+	requests     int
+	clientErrors int
+	flagged      bool
+}
+
+// Flags the users whose ratio of client errors exceeds the threshold within
+// a time window.
+type abuseDetector struct {
+	mutex       sync.Mutex
+	window      time.Duration
+	minRequests int
+	threshold   float64
+	activities  map[string]*userActivity
+}
+
+func newAbuseDetector(
+	window time.Duration,
+	minRequests int,
+	threshold float64,
+) *abuseDetector {
+	return &abuseDetector{
+		window:      window,
+		minRequests: minRequests,
+		threshold:   threshold,
+		activities:  map[string]*userActivity{},
+	}
+}
+
+// Records the outcome of a request and returns whether the user has been
+// flagged with it. The rejections of joe itself are not client errors of the
+// user and are not recorded.
+func (d *abuseDetector) record(
+	user string,
+	statusCode int,
+) (
+	bool,
+	float64,
+) {
+	// Detection is disabled
+	if d.window <= 0 || d.threshold <= 0 {
+		return false, 0
+	}
+	if statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests {
+		return false, 0
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	activity, ok := d.activities[user]
+	if !ok || now.Sub(activity.windowStart) > d.window {
+		if !ok && len(d.activities) >= maxTrackedUsers {
+			d.removeExpiredActivities(now)
+		}
+		activity = &userActivity{windowStart: now}
+		d.activities[user] = activity
+	}
+
+	activity.requests++
+	if statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError {
+		activity.clientErrors++
+	}
+
+	ratio := float64(activity.clientErrors) / float64(activity.requests)
+	if activity.flagged || activity.requests < d.minRequests || ratio <= d.threshold {
+		return false, ratio
+	}
+	activity.flagged = true
+	return true, ratio
+}
+
+func (d *abuseDetector) removeExpiredActivities(
+	now time.Time,
+) {
+	for user, activity := range d.activities {
+		if now.Sub(activity.windowStart) > d.window {
+			delete(d.activities, user)
+		}
+	}
+}
+
+// Rejects blocked and rate limited users. Returns false when the request
+// must not be processed any further.
+func checkUserAccess(
+	w http.ResponseWriter,
+	r *http.Request,
+	scope *requestScope,
+	user string,
+) bool {
+	if userBlockList.contains(user) {
+		log(logrus.WarnLevel, r.Context(), user, "User is blocked.")
+		scope.serverSpan.AddEvent("user.blocked")
+		recordAccessRejection(r.Context(), "blocked")
+		createErrorResponse(&w, http.StatusForbidden, errorCodeUserBlocked, "User is blocked.", scope)
+		return false
+	}
+
+	allowed, retryAfter := userRateLimiter.allow(user)
+	if !allowed {
+		retryAfterInSeconds := int(math.Ceil(retryAfter.Seconds()))
+		log(logrus.WarnLevel, r.Context(), user, "Rate limit is exceeded.")
+		scope.serverSpan.AddEvent("rate_limit.exceeded", trace.WithAttributes(
+			attribute.Int("retry_after", retryAfterInSeconds),
+		))
+		recordAccessRejection(r.Context(), "rate_limited")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterInSeconds))
+		createErrorResponse(&w, http.StatusTooManyRequests, errorCodeRateLimited, "Rate limit is exceeded.", scope)
+		return false
+	}
+	return true
+}
+
+// Feeds the outcome of the request to the abuse detector and reports the
+// user when it gets flagged.
+func detectAbuse(
+	ctx context.Context,
+	user string,
+	statusCode int,
+) {
+	flagged, ratio := userAbuseDetector.record(user, statusCode)
+	if !flagged {
+		return
+	}
+
+	// The user is only put on the span, one series per user would blow up the
+	// cardinality of the metric
+	log(logrus.WarnLevel, ctx, user, "User is flagged for abuse. Client error ratio: "+strconv.FormatFloat(ratio, 'f', 2, 64))
+	trace.SpanFromContext(ctx).AddEvent("abuse.flagged", trace.WithAttributes(
+		attribute.String("enduser.id", user),
+		attribute.Float64("ratio", ratio),
+	))
+	abuseFlaggedUsers.Add(ctx, 1)
+
+	if abuseAutoBlock {
+		blockDuration := abuseBlockDuration
+		if blockDuration <= 0 {
+			blockDuration = defaultAbuseBlockDuration
+		}
+		userBlockList.add(user, blockDuration)
+		log(logrus.WarnLevel, ctx, user, "User is added to the block list for "+blockDuration.String()+".")
+	}
+}
+
+// Returns the key under which the failed authentications of the caller are
+// tracked. Unauthenticated callers have no user, so their address is used.
+func getCallerAddress(
+	r *http.Request,
+) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "address:" + host
+}
+
+// Keeps track of the status code written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(
+	statusCode int,
+) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func encodeTestJwt(
+	t *testing.T,
+	header map[string]string,
+	claims map[string]interface{},
+	secret []byte,
+) string {
+	t.Helper()
+
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(headerJson) + "." + base64.RawURLEncoding.EncodeToString(claimsJson)
+
+	// Unsigned tokens end with an empty signature
+	if secret == nil {
+		return unsigned + "."
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJwt(t *testing.T) {
+	secret := []byte("secret")
+	hs256 := map[string]string{"alg": "HS256", "typ": "JWT"}
+	now := time.Now()
+
+	valid, err := signJwt("user", secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{
+			name:  "valid",
+			token: valid,
+		},
+		{
+			name:  "bad signature",
+			token: encodeTestJwt(t, hs256, map[string]interface{}{"sub": "user", "exp": now.Add(time.Minute).Unix()}, []byte("other")),
+			err:   errInvalidToken,
+		},
+		{
+			name:  "alg none",
+			token: encodeTestJwt(t, map[string]string{"alg": "none", "typ": "JWT"}, map[string]interface{}{"sub": "user", "exp": now.Add(time.Minute).Unix()}, nil),
+			err:   errInvalidToken,
+		},
+		{
+			name:  "alg none with signature",
+			token: encodeTestJwt(t, map[string]string{"alg": "none"}, map[string]interface{}{"sub": "user", "exp": now.Add(time.Minute).Unix()}, secret),
+			err:   errInvalidToken,
+		},
+		{
+			name:  "missing exp",
+			token: encodeTestJwt(t, hs256, map[string]interface{}{"sub": "user"}, secret),
+			err:   errInvalidToken,
+		},
+		{
+			name:  "expired exp",
+			token: encodeTestJwt(t, hs256, map[string]interface{}{"sub": "user", "exp": now.Add(-time.Minute).Unix()}, secret),
+			err:   errExpiredToken,
+		},
+		{
+			name:  "not yet valid",
+			token: encodeTestJwt(t, hs256, map[string]interface{}{"sub": "user", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}, secret),
+			err:   errInvalidToken,
+		},
+		{
+			name:  "missing subject",
+			token: encodeTestJwt(t, hs256, map[string]interface{}{"exp": now.Add(time.Minute).Unix()}, secret),
+			err:   errInvalidToken,
+		},
+		{
+			name:  "malformed",
+			token: "not-a-token",
+			err:   errInvalidToken,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := verifyJwt(test.token, secret)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if test.err == nil && claims.Subject != "user" {
+				t.Fatalf("expected subject user, got %q", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestApiKeyAuthenticator(t *testing.T) {
+	a := newApiKeyAuthenticator("key1:user1, key2:user2")
+
+	tests := []struct {
+		name    string
+		key     string
+		user    string
+		present bool
+		err     error
+	}{
+		{
+			name:    "first key",
+			key:     "key1",
+			user:    "user1",
+			present: true,
+		},
+		{
+			name:    "second key",
+			key:     "key2",
+			user:    "user2",
+			present: true,
+		},
+		{
+			name:    "wrong key",
+			key:     "key3",
+			present: true,
+			err:     errInvalidApiKey,
+		},
+		{
+			name:    "prefix of a key",
+			key:     "key",
+			present: true,
+			err:     errInvalidApiKey,
+		},
+		{
+			name: "missing key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			if test.key != "" {
+				header.Set("X-API-Key", test.key)
+			}
+
+			user, present, err := a.authenticate(header)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if present != test.present {
+				t.Fatalf("expected present %v, got %v", test.present, present)
+			}
+			if user != test.user {
+				t.Fatalf("expected user %q, got %q", test.user, user)
+			}
+		})
+	}
+}
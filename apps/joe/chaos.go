@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	chaosFaultNone      = "none"
+	chaosFaultLatency   = "latency"
+	chaosFaultReset     = "reset"
+	chaosFaultPartial   = "partial"
+	chaosFaultBandwidth = "bandwidth"
+
+	// Bandwidth is enforced in slices of this duration
+	chaosBandwidthSlice = 100 * time.Millisecond
+)
+
+var (
+	chaosProxyEnabled bool
+	chaosSchedule     string
+	chaosFaultRate    int
+	chaosLatency      time.Duration
+	chaosJitter       time.Duration
+	chaosBandwidth    int
+
+	donaldChaosProxy *chaosProxy
+)
+
+// Single phase of the chaos schedule. The faults of the phase are active for
+// its duration.
+type chaosPhase struct {
+	name     string
+	faults   map[string]bool
+	duration time.Duration
+}
+
+// Sits between joe and donald on the network level. Every connection to
+// donald is dialed through the proxy which injects latency, jitter,
+// connection resets, partial responses and bandwidth limits according to a
+// repeating schedule. The clients see the faults the way they would see them
+// on a real network, so they surface as failed client spans and within the
+// client duration histograms.
+type chaosProxy struct {
+	dialer    *net.Dialer
+	phases    []chaosPhase
+	cycle     time.Duration
+	startTime time.Time
+
+	mutex      sync.Mutex
+	random     *rand.Rand
+	phaseIndex int
+}
+
+func createChaosProxy() {
+	if !chaosProxyEnabled {
+		return
+	}
+
+	phases, err := parseChaosSchedule(chaosSchedule)
+	if err != nil {
+		panic(err)
+	}
+	donaldChaosProxy = newChaosProxy(phases)
+	logrus.Info("Calls to donald are routed through the chaos proxy. Schedule: " + chaosSchedule)
+}
+
+// Parses the schedule in the format "none:60000,latency:30000,reset+partial:15000"
+// where each phase consists of the faults joined with + and its duration in
+// milliseconds. The phases are repeated in the given order.
+func parseChaosSchedule(
+	schedule string,
+) (
+	[]chaosPhase,
+	error,
+) {
+	phases := []chaosPhase{}
+	for _, entry := range strings.Split(schedule, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, durationInMs, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, errors.New("invalid chaos schedule entry " + entry + ": duration is missing")
+		}
+		duration, err := strconv.ParseInt(durationInMs, 10, 64)
+		if err != nil || duration <= 0 {
+			return nil, errors.New("invalid chaos schedule entry " + entry + ": duration is not a positive number")
+		}
+
+		faults := map[string]bool{}
+		for _, fault := range strings.Split(name, "+") {
+			switch fault {
+			case chaosFaultLatency, chaosFaultReset, chaosFaultPartial, chaosFaultBandwidth:
+				faults[fault] = true
+			case chaosFaultNone:
+			default:
+				return nil, errors.New("invalid chaos schedule entry " + entry + ": unknown fault " + fault)
+			}
+		}
+
+		phases = append(phases, chaosPhase{
+			name:     name,
+			faults:   faults,
+			duration: time.Duration(duration) * time.Millisecond,
+		})
+	}
+
+	if len(phases) == 0 {
+		return nil, errors.New("chaos schedule has no phases")
+	}
+	return phases, nil
+}
+
+func newChaosProxy(
+	phases []chaosPhase,
+) *chaosProxy {
+	var cycle time.Duration
+	for _, phase := range phases {
+		cycle += phase.duration
+	}
+
+	return &chaosProxy{
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		phases:     phases,
+		cycle:      cycle,
+		startTime:  time.Now(),
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		phaseIndex: -1,
+	}
+}
+
+// Dials the target and wraps the connection so that the faults of the
+// current phase are applied to it.
+func (p *chaosProxy) dialContext(
+	ctx context.Context,
+	network string,
+	address string,
+) (
+	net.Conn,
+	error,
+) {
+	conn, err := p.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosConn{Conn: conn, proxy: p}, nil
+}
+
+// Returns the phase which is active right now. Transitions between the phases
+// are logged so that they can be correlated with the telemetry of the calls.
+func (p *chaosProxy) currentPhase() chaosPhase {
+	elapsed := time.Since(p.startTime) % p.cycle
+
+	index := 0
+	for elapsed >= p.phases[index].duration {
+		elapsed -= p.phases[index].duration
+		index++
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if index != p.phaseIndex {
+		p.phaseIndex = index
+		logrus.Info("Chaos phase " + p.phases[index].name + " started.")
+	}
+	return p.phases[index]
+}
+
+// Decides whether a fault which happens by chance hits the current operation.
+func (p *chaosProxy) roll() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.random.Intn(100) < chaosFaultRate
+}
+
+// Returns the latency plus a random jitter.
+func (p *chaosProxy) getDelay() time.Duration {
+	if chaosJitter <= 0 {
+		return chaosLatency
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return chaosLatency + time.Duration(p.random.Int63n(int64(chaosJitter)))
+}
+
+// Connection to donald which suffers from the faults of the proxy. Writes
+// carry the requests and are delayed or reset, reads carry the responses and
+// are throttled or cut off.
+type chaosConn struct {
+	net.Conn
+	proxy *chaosProxy
+
+	mutex sync.Mutex
+	err   error
+}
+
+func (c *chaosConn) Write(
+	b []byte,
+) (
+	int,
+	error,
+) {
+	if err := c.getErr(); err != nil {
+		return 0, err
+	}
+
+	phase := c.proxy.currentPhase()
+	if phase.faults[chaosFaultLatency] {
+		recordChaosFault(chaosFaultLatency)
+		time.Sleep(c.proxy.getDelay())
+	}
+
+	if phase.faults[chaosFaultReset] && c.proxy.roll() {
+		recordChaosFault(chaosFaultReset)
+		return 0, c.reset("write")
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *chaosConn) Read(
+	b []byte,
+) (
+	int,
+	error,
+) {
+	if err := c.getErr(); err != nil {
+		return 0, err
+	}
+
+	phase := c.proxy.currentPhase()
+	if !phase.faults[chaosFaultBandwidth] || chaosBandwidth <= 0 {
+		n, err := c.Conn.Read(b)
+		return c.cutOff(phase, n, err)
+	}
+
+	// Read no more than allowed within a slice and wait for the rest of it
+	sliceSize := int(int64(chaosBandwidth) * int64(chaosBandwidthSlice) / int64(time.Second))
+	if sliceSize < 1 {
+		sliceSize = 1
+	}
+	if len(b) > sliceSize {
+		b = b[:sliceSize]
+	}
+
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		recordChaosFault(chaosFaultBandwidth)
+		time.Sleep(time.Duration(int64(n) * int64(time.Second) / int64(chaosBandwidth)))
+	}
+	return c.cutOff(phase, n, err)
+}
+
+// Delivers only the first half of the received bytes and closes the
+// connection afterwards as if donald went away in the middle of the response.
+func (c *chaosConn) cutOff(
+	phase chaosPhase,
+	n int,
+	err error,
+) (
+	int,
+	error,
+) {
+	// Reads which were blocked while the connection was aborted report why
+	if err != nil {
+		if abortErr := c.getErr(); abortErr != nil {
+			return n, abortErr
+		}
+		return n, err
+	}
+	if n == 0 || !phase.faults[chaosFaultPartial] || !c.proxy.roll() {
+		return n, nil
+	}
+
+	recordChaosFault(chaosFaultPartial)
+	c.setErr(io.EOF)
+	c.Conn.Close()
+	return n / 2, nil
+}
+
+// Aborts the connection with a TCP reset instead of a graceful close.
+func (c *chaosConn) reset(
+	op string,
+) error {
+	err := &net.OpError{
+		Op:     op,
+		Net:    c.Conn.LocalAddr().Network(),
+		Source: c.Conn.LocalAddr(),
+		Addr:   c.Conn.RemoteAddr(),
+		Err:    os.NewSyscallError(op, syscall.ECONNRESET),
+	}
+	c.setErr(err)
+
+	if tcpConn, ok := c.Conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	c.Conn.Close()
+	return err
+}
+
+func (c *chaosConn) getErr() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
+
+func (c *chaosConn) setErr(
+	err error,
+) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func recordChaosFault(
+	fault string,
+) {
+	chaosFaults.Add(context.Background(), 1, metric.WithAttributes(attribute.String("fault", fault)))
+}
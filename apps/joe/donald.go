@@ -2,52 +2,166 @@ package main
 
 import (
 	"context"
-	"errors"
 	"io/ioutil"
 	"net/http"
-	"strconv"
-	"time"
+	"sync/atomic"
 
 	"github.com/sirupsen/logrus"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/metric/instrument"
-	"go.opentelemetry.io/otel/propagation"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 var (
-	httpClient         *http.Client
-	httpClientDuration instrument.Float64Histogram
+	donaldProtocol string
+
+	donald donaldClient
 )
 
-func performHttpCall(
+// Performs a single attempt of a call to donald over the configured protocol
+// and returns the received status code in terms of HTTP. Failures without a
+// response are reported as internal server error.
+type donaldClient interface {
+	performAttempt(
+		ctx context.Context,
+		httpMethod string,
+		user string,
+		path string,
+		reqParams map[string]string,
+	) (
+		int,
+		error,
+	)
+}
+
+func createDonaldClient() {
+	createChaosProxy()
+
+	switch donaldProtocol {
+	case "grpc":
+		donald = newGrpcDonaldClient()
+	default:
+		donald = newHttpDonaldClient()
+	}
+	donaldCircuitBreaker = newCircuitBreaker(circuitBreakerFailureThreshold, circuitBreakerOpenDuration)
+}
+
+func performDonaldCall(
 	ctx context.Context,
 	httpMethod string,
 	user string,
+	path string,
 	reqParams map[string]string,
 ) error {
 
-	log(logrus.InfoLevel, ctx, user, "Preparing HTTP call...")
+	// Fail fast when donald is known to be unhealthy
+	if !donaldCircuitBreaker.allow() {
+		log(logrus.WarnLevel, ctx, user, errCircuitOpen.Error())
+		return errCircuitOpen
+	}
+
+	atomic.AddInt64(&inFlightDonaldCalls, 1)
+	defer atomic.AddInt64(&inFlightDonaldCalls, -1)
+
+	maxRetries := 0
+	if isIdempotentMethod(httpMethod) {
+		maxRetries = donaldMaxRetries
+	}
+
+	var statusCode int
+	var err error
+	for resendCount := 0; ; resendCount++ {
+		statusCode, err = performAttempt(ctx, httpMethod, user, path, reqParams, resendCount)
+		if err == nil || resendCount >= maxRetries || !isRetryableAttempt(ctx, err) {
+			break
+		}
+
+		log(logrus.WarnLevel, ctx, user, "Call to donald failed. Retrying...")
+		if waitErr := waitForRetry(ctx, resendCount); waitErr != nil {
+			break
+		}
+	}
 
-	// Create request propagation
-	carrier := propagation.HeaderCarrier(http.Header{})
-	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	donaldCircuitBreaker.record(err == nil || statusCode < http.StatusInternalServerError)
+	if err != nil {
+		return err
+	}
+
+	log(logrus.InfoLevel, ctx, user, "Call to donald is performed successfully.")
+	return nil
+}
+
+func performAttempt(
+	ctx context.Context,
+	httpMethod string,
+	user string,
+	path string,
+	reqParams map[string]string,
+	resendCount int,
+) (
+	int,
+	error,
+) {
+	// Limit the duration of each attempt individually
+	requestTimeout := donaldRequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = defaultDonaldRequestTimeout
+	}
+	attemptCtx, cancel := context.WithTimeout(withResendCount(ctx, resendCount), requestTimeout)
+	defer cancel()
+	return donald.performAttempt(attemptCtx, httpMethod, user, path, reqParams)
+}
+
+type httpDonaldClient struct {
+	client *http.Client
+}
+
+func newHttpDonaldClient() *httpDonaldClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = createClientTlsConfig()
+	if donaldChaosProxy != nil {
+		transport.DialContext = donaldChaosProxy.dialContext
+	}
+
+	return &httpDonaldClient{
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(&resendCountTransport{base: &transportInfoTransport{base: transport}}),
+		},
+	}
+}
+
+func (c *httpDonaldClient) performAttempt(
+	ctx context.Context,
+	httpMethod string,
+	user string,
+	path string,
+	reqParams map[string]string,
+) (
+	int,
+	error,
+) {
+
+	log(logrus.InfoLevel, ctx, user, "Preparing HTTP call...")
 
 	// Create HTTP request with trace context
 	req, err := http.NewRequestWithContext(
 		ctx, httpMethod,
-		"http://"+donaldEndpoint+":"+donaldPort+"/api",
+		getDonaldScheme()+"://"+donaldEndpoint+":"+donaldPort+path,
 		nil,
 	)
 	if err != nil {
 		log(logrus.ErrorLevel, ctx, user, err.Error())
-		return err
+		return http.StatusInternalServerError, err
 	}
 
 	// Add headers
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-User-ID", user)
+	credentials, err := getDonaldCredentials(user)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		return http.StatusInternalServerError, err
+	}
+	for key, value := range credentials {
+		req.Header.Set(key, value)
+	}
 
 	// Add request params
 	qps := req.URL.Query()
@@ -60,16 +174,12 @@ func performHttpCall(
 	}
 	log(logrus.InfoLevel, ctx, user, "HTTP call is prepared.")
 
-	// Start timer
-	requestStartTime := time.Now()
-
 	// Perform HTTP request
 	log(logrus.InfoLevel, ctx, user, "Performing HTTP call")
-	res, err := httpClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
 		log(logrus.ErrorLevel, ctx, user, err.Error())
-		recordClientDuration(ctx, httpMethod, http.StatusInternalServerError, requestStartTime)
-		return err
+		return http.StatusInternalServerError, err
 	}
 	defer res.Body.Close()
 
@@ -77,38 +187,23 @@ func performHttpCall(
 	resBody, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		log(logrus.ErrorLevel, ctx, user, err.Error())
-		recordClientDuration(ctx, httpMethod, res.StatusCode, requestStartTime)
-		return err
+		return res.StatusCode, err
 	}
 
 	// Check status code
 	if res.StatusCode != http.StatusOK {
 		log(logrus.ErrorLevel, ctx, user, string(resBody))
-		recordClientDuration(ctx, httpMethod, res.StatusCode, requestStartTime)
-		return errors.New("call to donald returned not ok status")
+		return res.StatusCode, newDonaldError(res.StatusCode, resBody)
 	}
 
-	recordClientDuration(ctx, httpMethod, res.StatusCode, requestStartTime)
-	log(logrus.InfoLevel, ctx, user, "HTTP call is performed successfully.")
-	return nil
+	return res.StatusCode, nil
 }
 
-func recordClientDuration(
+// Checks whether a failed attempt is worth retrying. Attempts are not
+// retried when the caller has already given up.
+func isRetryableAttempt(
 	ctx context.Context,
-	httpMethod string,
-	statusCode int,
-	startTime time.Time,
-) {
-	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
-	httpserverPortAsInt, _ := strconv.Atoi(donaldPort)
-	attributes := attribute.NewSet(
-		semconv.HTTPSchemeHTTP,
-		semconv.HTTPFlavorKey.String("1.1"),
-		semconv.HTTPMethod(httpMethod),
-		semconv.NetPeerName(donaldEndpoint),
-		semconv.NetPeerPort(httpserverPortAsInt),
-		semconv.HTTPStatusCode(statusCode),
-	)
-
-	httpClientDuration.Record(ctx, elapsedTime, attributes.ToSlice()...)
+	err error,
+) bool {
+	return ctx.Err() == nil && isRetryableError(err)
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	errorCodeNotFound          = "not_found"
+	errorCodeMethodNotAllowed  = "method_not_allowed"
+	errorCodeInvalidDataFormat = "invalid_data_format"
+	errorCodeDonaldUnavailable = "donald_unavailable"
+	errorCodeDonaldTimeout     = "donald_timeout"
+	errorCodeDonaldUnreachable = "donald_unreachable"
+	errorCodeDonaldError       = "donald_error"
+)
+
+// Body of every failed response so that the callers can tell what went
+// wrong and in which trace.
+type errorResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	TraceId string       `json:"traceId,omitempty"`
+	Errors  []fieldError `json:"errors,omitempty"`
+}
+
+// Error which is returned when donald responds with a not ok status.
+type donaldError struct {
+	statusCode int
+	response   errorResponse
+}
+
+func (e *donaldError) Error() string {
+	return "call to donald returned not ok status " + strconv.Itoa(e.statusCode) + ": " + e.response.Code
+}
+
+func newDonaldError(
+	statusCode int,
+	body []byte,
+) *donaldError {
+	var res errorResponse
+	err := json.Unmarshal(body, &res)
+	if err != nil || res.Code == "" {
+		res = errorResponse{
+			Code:    errorCodeDonaldError,
+			Message: string(body),
+		}
+	}
+	return &donaldError{
+		statusCode: statusCode,
+		response:   res,
+	}
+}
+
+// Maps the error of the call to donald to the status code, error code and
+// message which joe responds with. Client errors are forwarded as they are
+// whereas server errors are reported as gateway errors.
+func getDonaldErrorResponse(
+	err error,
+) (
+	int,
+	string,
+	string,
+) {
+	var dErr *donaldError
+	switch {
+	case errors.As(err, &dErr):
+		switch {
+		case dErr.statusCode < http.StatusInternalServerError:
+			return dErr.statusCode, dErr.response.Code, dErr.response.Message
+		case dErr.statusCode == http.StatusServiceUnavailable:
+			return http.StatusServiceUnavailable, dErr.response.Code, dErr.response.Message
+		default:
+			return http.StatusBadGateway, dErr.response.Code, dErr.response.Message
+		}
+	case errors.Is(err, errCircuitOpen):
+		return http.StatusServiceUnavailable, errorCodeDonaldUnavailable, "Donald is currently unavailable."
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, errorCodeDonaldTimeout, "Donald did not respond in time."
+	default:
+		return http.StatusBadGateway, errorCodeDonaldUnreachable, "Donald could not be reached."
+	}
+}
+
+func createErrorResponse(
+	w *http.ResponseWriter,
+	statusCode int,
+	code string,
+	message string,
+	scope *requestScope,
+) {
+	writeErrorResponse(w, statusCode, errorResponse{
+		Code:    code,
+		Message: message,
+		TraceId: scope.traceId(),
+	})
+}
+
+func createValidationErrorResponse(
+	w *http.ResponseWriter,
+	fieldErrors []fieldError,
+	scope *requestScope,
+) {
+	writeErrorResponse(w, http.StatusBadRequest, errorResponse{
+		Code:    errorCodeInvalidRequest,
+		Message: "Request is invalid.",
+		TraceId: scope.traceId(),
+		Errors:  fieldErrors,
+	})
+}
+
+func writeErrorResponse(
+	w *http.ResponseWriter,
+	statusCode int,
+	res errorResponse,
+) {
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		body = []byte(res.Message)
+	}
+
+	(*w).Header().Set("Content-Type", "application/json")
+	createHttpResponse(w, statusCode, body)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/opentelemetry-playground/golang/apps/simulator/pb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	donaldGrpcPort string
+)
+
+// Calls the gRPC API of donald. The HTTP methods and paths which joe serves
+// are mapped to the corresponding RPCs.
+type grpcDonaldClient struct {
+	client pb.NameServiceClient
+}
+
+func newGrpcDonaldClient() *grpcDonaldClient {
+	var creds credentials.TransportCredentials = insecure.NewCredentials()
+	if tlsConfig := createClientTlsConfig(); tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(
+			otelgrpc.UnaryClientInterceptor(),
+			annotateClientSpan,
+		),
+	}
+	if donaldChaosProxy != nil {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return donaldChaosProxy.dialContext(ctx, "tcp", address)
+		}))
+	}
+
+	conn, err := grpc.Dial(donaldEndpoint+":"+donaldGrpcPort, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return &grpcDonaldClient{
+		client: pb.NewNameServiceClient(conn),
+	}
+}
+
+func (c *grpcDonaldClient) performAttempt(
+	ctx context.Context,
+	httpMethod string,
+	user string,
+	path string,
+	reqParams map[string]string,
+) (
+	int,
+	error,
+) {
+
+	log(logrus.InfoLevel, ctx, user, "Preparing gRPC call...")
+
+	// Add credentials as metadata
+	credentials, err := getDonaldCredentials(user)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		return http.StatusInternalServerError, err
+	}
+	for key, value := range credentials {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(key), value)
+	}
+
+	// Get the id from the path (/api/{id})
+	var id int64
+	if segments := splitPath(path); len(segments) == 2 {
+		id, err = strconv.ParseInt(segments[1], 10, 64)
+		if err != nil {
+			log(logrus.ErrorLevel, ctx, user, err.Error())
+			return http.StatusBadRequest, err
+		}
+	}
+
+	simulation := &pb.Simulation{
+		DatabaseConnectionError:      reqParams["databaseConnectionError"] == "true",
+		TableDoesNotExistError:       reqParams["tableDoesNotExistError"] == "true",
+		SchemaNotFoundInCacheWarning: reqParams["schemaNotFoundInCacheWarning"] == "true",
+		SlowQuery:                    reqParams["slowQuery"] == "true",
+		LockContention:               reqParams["lockContention"] == "true",
+		Deadlock:                     reqParams["deadlock"] == "true",
+	}
+	log(logrus.InfoLevel, ctx, user, "gRPC call is prepared.")
+
+	// Perform gRPC call
+	log(logrus.InfoLevel, ctx, user, "Performing gRPC call")
+	switch httpMethod {
+	case http.MethodGet:
+		_, err = c.client.ListNames(ctx, &pb.ListNamesRequest{
+			Id:         id,
+			Simulation: simulation,
+		})
+	case http.MethodDelete:
+		_, err = c.client.DeleteNames(ctx, &pb.DeleteNamesRequest{
+			Id:         id,
+			Simulation: simulation,
+		})
+	default:
+		err = errors.New("method " + httpMethod + " is not supported over gRPC")
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		return http.StatusMethodNotAllowed, err
+	}
+
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		return getGrpcCallError(ctx, err)
+	}
+	return http.StatusOK, nil
+}
+
+// Converts the status of a failed call into the status code and the error
+// of an HTTP call. Statuses with the error code of donald are returned as
+// donald errors, the others did not reach donald.
+func getGrpcCallError(
+	ctx context.Context,
+	err error,
+) (
+	int,
+	error,
+) {
+	if ctx.Err() != nil {
+		return http.StatusInternalServerError, ctx.Err()
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return http.StatusInternalServerError, err
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			statusCode := getHttpStatusCode(st.Code())
+			return statusCode, &donaldError{
+				statusCode: statusCode,
+				response: errorResponse{
+					Code:    info.Reason,
+					Message: st.Message(),
+				},
+			}
+		}
+	}
+	return http.StatusInternalServerError, err
+}
+
+func getHttpStatusCode(
+	code codes.Code,
+) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Adds the resend count and the TLS handshake failures to the client span
+// which is started by the otelgrpc interceptor before. The duration of the
+// call is recorded within the client span, so that the sampled measurements
+// are exported as exemplars which link to their traces.
+func annotateClientSpan(
+	ctx context.Context,
+	method string,
+	req interface{},
+	reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	span := trace.SpanFromContext(ctx)
+	if resendCount, ok := ctx.Value(resendCountKey{}).(int); ok && resendCount > 0 {
+		span.SetAttributes(attribute.Int("rpc.resend_count", resendCount))
+	}
+
+	startTime := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	recordRpcClientDuration(ctx, method, status.Code(err), startTime)
+
+	if err != nil && isTlsError(err) {
+		span.AddEvent("tls.handshake.failure", trace.WithAttributes(
+			attribute.String("reason", err.Error()),
+		))
+		setSpanError(span, err.Error())
+	}
+	return err
+}
+
+func recordRpcClientDuration(
+	ctx context.Context,
+	method string,
+	code codes.Code,
+	startTime time.Time,
+) {
+	elapsedTime := float64(time.Since(startTime)) / float64(time.Millisecond)
+
+	// The full method is in the form of /{service}/{method}
+	service, rpcMethod, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	rpcClientDuration.Record(ctx, elapsedTime, metric.WithAttributes(
+		semconv.RPCSystemGRPC,
+		semconv.RPCService(service),
+		semconv.RPCMethod(rpcMethod),
+		semconv.RPCGRPCStatusCodeKey.Int(int(code)),
+		semconv.NetPeerName(donaldEndpoint),
+	))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestScopeKey struct{}
+
+// Instrumentation of a single request. The server span is owned by the
+// otelhttp handler which starts it, ends it exactly once and derives the HTTP
+// status code and the span status from the written response. Child spans are
+// started through the scope.
+type requestScope struct {
+	serverSpan trace.Span
+	tracer     trace.Tracer
+	route      string
+	pathParams map[string]string
+}
+
+func newRequestScope(
+	serverSpan trace.Span,
+) *requestScope {
+	return &requestScope{
+		serverSpan: serverSpan,
+		tracer:     otel.GetTracerProvider().Tracer(appName),
+		pathParams: map[string]string{},
+	}
+}
+
+// Returns the scope of the request which the context belongs to. Outside of
+// a request, a scope for the span within the context is created.
+func getRequestScope(
+	ctx context.Context,
+) *requestScope {
+	if scope, ok := ctx.Value(requestScopeKey{}).(*requestScope); ok {
+		return scope
+	}
+	return newRequestScope(trace.SpanFromContext(ctx))
+}
+
+func instrumentHandler(
+	operation string,
+	handler func(http.ResponseWriter, *http.Request, *requestScope),
+	opts ...otelhttp.Option,
+) http.Handler {
+	return otelhttp.NewHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&inFlightRequests, 1)
+			defer atomic.AddInt64(&inFlightRequests, -1)
+
+			scope := newRequestScope(trace.SpanFromContext(r.Context()))
+			scope.serverSpan.SetAttributes(getTransportAttributes(r.ProtoMajor, r.ProtoMinor, r.TLS)...)
+			if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+				scope.serverSpan.SetAttributes(attribute.String("tls.client.subject", r.TLS.PeerCertificates[0].Subject.String()))
+			}
+			ctx := context.WithValue(r.Context(), requestScopeKey{}, scope)
+			handler(w, r.WithContext(ctx), scope)
+		}),
+		operation,
+		opts...,
+	)
+}
+
+func (s *requestScope) startInternalSpan(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (
+	context.Context,
+	trace.Span,
+) {
+	return s.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (s *requestScope) startClientSpan(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (
+	context.Context,
+	trace.Span,
+) {
+	return s.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (s *requestScope) traceId() string {
+	if !s.serverSpan.SpanContext().HasTraceID() {
+		return ""
+	}
+	return s.serverSpan.SpanContext().TraceID().String()
+}
+
+// Describes the transport which the request was actually made over. The
+// http.scheme is already derived from it by otelhttp.
+func getTransportAttributes(
+	protoMajor int,
+	protoMinor int,
+	connState *tls.ConnectionState,
+) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("net.protocol.name", "http"),
+		attribute.String("net.protocol.version", strconv.Itoa(protoMajor)+"."+strconv.Itoa(protoMinor)),
+	}
+	if connState == nil {
+		return attrs
+	}
+
+	attrs = append(attrs,
+		attribute.String("tls.protocol.version", getTlsVersionName(connState.Version)),
+		attribute.String("tls.cipher", tls.CipherSuiteName(connState.CipherSuite)),
+	)
+	return attrs
+}
+
+// Marks the span as failed with the given description.
+func setSpanError(
+	span trace.Span,
+	description string,
+) {
+	span.SetStatus(codes.Error, description)
+}
@@ -6,11 +6,10 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
-	"go.opentelemetry.io/otel/metric/global"
 )
 
 var (
@@ -54,12 +53,31 @@ func main() {
 	mp := newMetricProvider(ctx)
 	defer shutdownMetricProvider(ctx, mp)
 
+	// Create custom instruments
+	createInstruments()
+
+	// Create rate limiter, block list and abuse detector
+	createAbuseProtection()
+
+	// Create authenticators
+	createAuthenticators()
+
+	// Create client for donald
+	createDonaldClient()
+
+	// Create broker for the asynchronous commands
+	createMessageBroker()
+
 	// Simulate
 	go simulate()
 
 	// Serve
-	http.Handle("/api", otelhttp.NewHandler(http.HandlerFunc(handler), "api"))
-	http.ListenAndServe(":"+appPort, nil)
+	router := newRouter()
+	router.handle(http.MethodGet, "/api", authenticate(handler))
+	router.handle(http.MethodDelete, "/api", authenticate(handler))
+	router.handle(http.MethodGet, "/api/{id}", authenticate(handler))
+	router.handle(http.MethodDelete, "/api/{id}", authenticate(handler))
+	listenAndServe(router.handler())
 }
 
 func parseFlags() {
@@ -69,9 +87,70 @@ func parseFlags() {
 	donaldRequestInterval = os.Getenv("DONALD_REQUEST_INTERVAL")
 	donaldEndpoint = os.Getenv("DONALD_ENDPOINT")
 	donaldPort = os.Getenv("DONALD_PORT")
+	donaldProtocol = os.Getenv("DONALD_PROTOCOL")
+	donaldGrpcPort = os.Getenv("DONALD_GRPC_PORT")
+	donaldAsyncCommands, _ = strconv.ParseBool(os.Getenv("DONALD_ASYNC_COMMANDS"))
+
+	messagingBackend = os.Getenv("MESSAGING_BACKEND")
+	messagingServer = os.Getenv("MESSAGING_SERVER")
+	messagingPort = os.Getenv("MESSAGING_PORT")
+	messagingSubject = os.Getenv("MESSAGING_SUBJECT")
+	donaldJwtSecret = os.Getenv("DONALD_JWT_SECRET")
+	donaldTls, _ = strconv.ParseBool(os.Getenv("DONALD_TLS"))
+	donaldTlsCaFile = os.Getenv("DONALD_TLS_CA_FILE")
+	donaldTlsCertFile = os.Getenv("DONALD_TLS_CERT_FILE")
+	donaldTlsKeyFile = os.Getenv("DONALD_TLS_KEY_FILE")
+	donaldTlsServerName = os.Getenv("DONALD_TLS_SERVER_NAME")
+
+	donaldRequestTimeoutInMs, _ := strconv.ParseInt(os.Getenv("DONALD_REQUEST_TIMEOUT"), 10, 64)
+	donaldRequestTimeout = time.Duration(donaldRequestTimeoutInMs) * time.Millisecond
+	donaldMaxRetries, _ = strconv.Atoi(os.Getenv("DONALD_MAX_RETRIES"))
+	donaldRetryBackoffInMs, _ := strconv.ParseInt(os.Getenv("DONALD_RETRY_BACKOFF"), 10, 64)
+	donaldRetryBackoff = time.Duration(donaldRetryBackoffInMs) * time.Millisecond
+	donaldRetryBackoffLimitInMs, _ := strconv.ParseInt(os.Getenv("DONALD_RETRY_BACKOFF_LIMIT"), 10, 64)
+	donaldRetryBackoffLimit = time.Duration(donaldRetryBackoffLimitInMs) * time.Millisecond
+
+	circuitBreakerFailureThreshold, _ = strconv.Atoi(os.Getenv("CIRCUIT_BREAKER_FAILURE_THRESHOLD"))
+	circuitBreakerOpenDurationInMs, _ := strconv.ParseInt(os.Getenv("CIRCUIT_BREAKER_OPEN_DURATION"), 10, 64)
+	circuitBreakerOpenDuration = time.Duration(circuitBreakerOpenDurationInMs) * time.Millisecond
+
+	chaosProxyEnabled, _ = strconv.ParseBool(os.Getenv("CHAOS_PROXY_ENABLED"))
+	chaosSchedule = os.Getenv("CHAOS_SCHEDULE")
+	chaosFaultRate, _ = strconv.Atoi(os.Getenv("CHAOS_FAULT_RATE"))
+	chaosLatencyInMs, _ := strconv.ParseInt(os.Getenv("CHAOS_LATENCY"), 10, 64)
+	chaosLatency = time.Duration(chaosLatencyInMs) * time.Millisecond
+	chaosJitterInMs, _ := strconv.ParseInt(os.Getenv("CHAOS_JITTER"), 10, 64)
+	chaosJitter = time.Duration(chaosJitterInMs) * time.Millisecond
+	chaosBandwidth, _ = strconv.Atoi(os.Getenv("CHAOS_BANDWIDTH"))
 
 	considerPreprocessingSpans, _ = strconv.ParseBool(os.Getenv("CONSIDER_PREPROCESSING_SPANS"))
 
+	rateLimitPerSecond, _ = strconv.ParseFloat(os.Getenv("RATE_LIMIT_PER_SECOND"), 64)
+	rateLimitBurst, _ = strconv.Atoi(os.Getenv("RATE_LIMIT_BURST"))
+	blockedUsers = os.Getenv("BLOCKED_USERS")
+
+	abuseDetectionWindowInMs, _ := strconv.ParseInt(os.Getenv("ABUSE_DETECTION_WINDOW"), 10, 64)
+	abuseDetectionWindow = time.Duration(abuseDetectionWindowInMs) * time.Millisecond
+	abuseDetectionMinRequests, _ = strconv.Atoi(os.Getenv("ABUSE_DETECTION_MIN_REQUESTS"))
+	abuseDetectionThreshold, _ = strconv.ParseFloat(os.Getenv("ABUSE_DETECTION_THRESHOLD"), 64)
+	abuseAutoBlock, _ = strconv.ParseBool(os.Getenv("ABUSE_AUTO_BLOCK"))
+	abuseBlockDurationInMs, _ := strconv.ParseInt(os.Getenv("ABUSE_BLOCK_DURATION"), 10, 64)
+	abuseBlockDuration = time.Duration(abuseBlockDurationInMs) * time.Millisecond
+
+	authMethods = os.Getenv("AUTH_METHODS")
+	authApiKeys = os.Getenv("AUTH_API_KEYS")
+	authJwtSecret = os.Getenv("AUTH_JWT_SECRET")
+
+	tlsCertFile = os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile = os.Getenv("TLS_KEY_FILE")
+	tlsClientCaFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	tlsReloadIntervalInMs, _ := strconv.ParseInt(os.Getenv("TLS_RELOAD_INTERVAL"), 10, 64)
+	tlsReloadInterval = time.Duration(tlsReloadIntervalInMs) * time.Millisecond
+
+	metricViewsFile = os.Getenv("METRIC_VIEWS_FILE")
+	metricExporters = os.Getenv("METRIC_EXPORTERS")
+	prometheusPort = os.Getenv("PROMETHEUS_PORT")
+
 	logLevel = os.Getenv("LOG_LEVEL")
 	logWithContext, _ = strconv.ParseBool(os.Getenv("LOG_WITH_CONTEXT"))
 }
@@ -83,53 +162,75 @@ func simulate() {
 		logrus.Error(err.Error())
 	}
 
-	httpClient = &http.Client{
-		Transport: otelhttp.NewTransport(http.DefaultTransport),
-		Timeout:   time.Duration(30 * time.Second),
-	}
-
-	httpClientDuration, err = global.MeterProvider().
-		Meter(appName).
-		Float64Histogram("http.client.duration")
-	if err != nil {
-		logrus.Error(err.Error())
-		return
-	}
-
 	// Initialize random number generator
 	randomizer := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	// LIST simulator
-	go func() {
+	startSimulator(func() {
 		for {
 
 			// Make request after each interval
 			time.Sleep(time.Duration(interval) * time.Millisecond)
 
 			// List
-			performHttpCall(
+			performDonaldCall(
 				context.Background(),
 				http.MethodGet,
 				users[randomizer.Intn(len(users))],
+				"/api",
 				map[string]string{},
 			)
 		}
-	}()
+	})
 
 	// DELETE simulator
-	go func() {
+	startSimulator(func() {
 		for {
 
 			// Make request after each interval * 4
 			time.Sleep(4 * time.Duration(interval) * time.Millisecond)
 
 			// Delete
-			performHttpCall(
+			user := users[randomizer.Intn(len(users))]
+			if donaldAsyncCommands {
+				publishDonaldCommand(context.Background(), user, commandTypeDelete, "", "", map[string]string{})
+				continue
+			}
+			performDonaldCall(
 				context.Background(),
 				http.MethodDelete,
-				users[randomizer.Intn(len(users))],
+				user,
+				"/api",
 				map[string]string{},
 			)
 		}
+	})
+
+	// CREATE simulator
+	if donaldAsyncCommands {
+		startSimulator(func() {
+			createRandomizer := rand.New(rand.NewSource(time.Now().UnixNano()))
+			for {
+
+				// Make request after each interval * 2
+				time.Sleep(2 * time.Duration(interval) * time.Millisecond)
+
+				// Create
+				user := users[createRandomizer.Intn(len(users))]
+				publishDonaldCommand(context.Background(), user, commandTypeCreate, "", user, map[string]string{})
+			}
+		})
+	}
+}
+
+// Runs the loop of a simulator within a goroutine of its own which is counted
+// as long as the loop runs.
+func startSimulator(
+	loop func(),
+) {
+	atomic.AddInt64(&simulatorGoroutines, 1)
+	go func() {
+		defer atomic.AddInt64(&simulatorGoroutines, -1)
+		loop()
 	}()
 }
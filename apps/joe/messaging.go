@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	commandTypeDelete = "DELETE"
+	commandTypeCreate = "CREATE"
+
+	errorCodeMessagingUnavailable = "messaging_unavailable"
+)
+
+var (
+	messagingBackend    string
+	messagingServer     string
+	messagingPort       string
+	messagingSubject    string
+	donaldAsyncCommands bool
+
+	broker messageBroker
+)
+
+// Backend of the asynchronous commands. Further backends (e.g. Kafka
+// compatible ones) only need to implement this interface.
+type messageBroker interface {
+	name() string
+	publish(subject string, header map[string]string, data []byte) error
+	subscribe(subject string, queue string, handler func(*brokerMessage)) error
+	close() error
+}
+
+// Command which is published to donald instead of calling it synchronously.
+type donaldCommand struct {
+	Type        string            `json:"type"`
+	Id          int64             `json:"id,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+	PublishedAt int64             `json:"publishedAt"`
+}
+
+func createMessageBroker() {
+	switch messagingBackend {
+	case "":
+		if donaldAsyncCommands {
+			panic("asynchronous commands require a messaging backend")
+		}
+	case "nats":
+		broker = newNatsBroker(messagingServer + ":" + messagingPort)
+	default:
+		panic("unknown messaging backend: " + messagingBackend)
+	}
+}
+
+// Publishes the command with the credentials of the user and the trace
+// context within the message header.
+func publishDonaldCommand(
+	ctx context.Context,
+	user string,
+	commandType string,
+	id string,
+	name string,
+	reqParams map[string]string,
+) error {
+	command := donaldCommand{
+		Type:        commandType,
+		Name:        name,
+		Parameters:  reqParams,
+		PublishedAt: time.Now().UnixMilli(),
+	}
+	if id != "" {
+		command.Id, _ = strconv.ParseInt(id, 10, 64)
+	}
+
+	data, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+
+	ctx, span := otel.GetTracerProvider().Tracer(appName).Start(
+		ctx,
+		messagingSubject+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(broker.name()),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(messagingSubject),
+			semconv.MessagingMessagePayloadSizeBytes(len(data)),
+			attribute.String("command.type", commandType),
+		),
+	)
+	defer span.End()
+
+	log(logrus.InfoLevel, ctx, user, "Publishing command: "+commandType)
+
+	header, err := getDonaldCredentials(user)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, err.Error())
+		setSpanError(span, err.Error())
+		return err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(header))
+
+	err = broker.publish(messagingSubject, header, data)
+	recordCommandPublished(ctx, commandType, err == nil)
+	if err != nil {
+		log(logrus.ErrorLevel, ctx, user, "Command could not be published: "+err.Error())
+		setSpanError(span, err.Error())
+		return err
+	}
+
+	log(logrus.InfoLevel, ctx, user, "Command is published.")
+	return nil
+}
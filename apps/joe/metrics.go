@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	rpcClientDuration        metric.Float64Histogram
+	preprocessingFailures    metric.Int64Counter
+	validationFailures       metric.Int64Counter
+	accessRejections         metric.Int64Counter
+	abuseFlaggedUsers        metric.Int64Counter
+	authFailures             metric.Int64Counter
+	messagesPublished        metric.Int64Counter
+	chaosFaults              metric.Int64Counter
+	circuitBreakerStateGauge metric.Int64ObservableGauge
+	simulatorGoroutinesGauge metric.Int64ObservableGauge
+	inFlightRequestsGauge    metric.Int64ObservableGauge
+	inFlightDonaldCallsGauge metric.Int64ObservableGauge
+
+	// Updated atomically
+	simulatorGoroutines int64
+	inFlightRequests    int64
+	inFlightDonaldCalls int64
+)
+
+func createInstruments() {
+	meter := otel.GetMeterProvider().Meter(appName)
+
+	var err error
+	rpcClientDuration, err = meter.Float64Histogram("rpc.client.duration")
+	if err != nil {
+		panic(err)
+	}
+
+	preprocessingFailures, err = meter.Int64Counter("preprocessing.failures")
+	if err != nil {
+		panic(err)
+	}
+
+	validationFailures, err = meter.Int64Counter("validation.failures")
+	if err != nil {
+		panic(err)
+	}
+
+	accessRejections, err = meter.Int64Counter("access.rejections")
+	if err != nil {
+		panic(err)
+	}
+
+	abuseFlaggedUsers, err = meter.Int64Counter("abuse.flagged_users")
+	if err != nil {
+		panic(err)
+	}
+
+	authFailures, err = meter.Int64Counter("auth.failures")
+	if err != nil {
+		panic(err)
+	}
+
+	messagesPublished, err = meter.Int64Counter("messaging.published")
+	if err != nil {
+		panic(err)
+	}
+
+	chaosFaults, err = meter.Int64Counter("chaos.faults")
+	if err != nil {
+		panic(err)
+	}
+
+	// 0 -> closed, 1 -> half open, 2 -> open
+	circuitBreakerStateGauge, err = meter.Int64ObservableGauge(
+		"circuit_breaker.state",
+		metric.WithInt64Callback(observeCircuitBreakerState),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	simulatorGoroutinesGauge, err = meter.Int64ObservableGauge(
+		"simulator.goroutines",
+		metric.WithInt64Callback(observeCounter(&simulatorGoroutines)),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	inFlightRequestsGauge, err = meter.Int64ObservableGauge(
+		"http.server.requests.in_flight",
+		metric.WithInt64Callback(observeCounter(&inFlightRequests)),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	inFlightDonaldCallsGauge, err = meter.Int64ObservableGauge(
+		"donald.calls.in_flight",
+		metric.WithInt64Callback(observeCounter(&inFlightDonaldCalls)),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Observes the current value of a counter which is updated atomically.
+func observeCounter(
+	counter *int64,
+) metric.Int64Callback {
+	return func(ctx context.Context, observer metric.Int64Observer) error {
+		observer.Observe(atomic.LoadInt64(counter))
+		return nil
+	}
+}
+
+func recordAuthFailure(
+	ctx context.Context,
+	method string,
+	reason string,
+) {
+	authFailures.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("auth.method", method),
+			attribute.String("reason", reason),
+		),
+	)
+}
+
+func recordCommandPublished(
+	ctx context.Context,
+	commandType string,
+	success bool,
+) {
+	messagesPublished.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("messaging.destination.name", messagingSubject),
+			attribute.String("command.type", commandType),
+			attribute.Bool("success", success),
+		),
+	)
+}
+
+func recordAccessRejection(
+	ctx context.Context,
+	reason string,
+) {
+	accessRejections.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+func observeCircuitBreakerState(
+	ctx context.Context,
+	observer metric.Int64Observer,
+) error {
+	if donaldCircuitBreaker == nil {
+		return nil
+	}
+	state := donaldCircuitBreaker.currentState()
+	observer.Observe(int64(state), metric.WithAttributes(attribute.String("net.peer.name", donaldEndpoint)))
+	return nil
+}
+
+func recordPreprocessingFailure(
+	ctx context.Context,
+	httpMethod string,
+	reason string,
+) {
+	preprocessingFailures.Add(ctx, 1,
+		metric.WithAttributes(
+			attribute.String("http.method", httpMethod),
+			attribute.String("reason", reason),
+		),
+	)
+}
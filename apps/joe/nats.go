@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	natsHeaderLine   = "NATS/1.0"
+	natsReconnectGap = time.Second
+
+	// Messages which are handled at the same time, further ones are not read
+	// from the connection until a handler is done
+	natsMaxInFlightMessages = 32
+)
+
+// Message which is exchanged over the broker. The header carries the trace
+// context and the credentials.
+type brokerMessage struct {
+	subject string
+	header  map[string]string
+	data    []byte
+}
+
+type natsSubscription struct {
+	sid     string
+	subject string
+	queue   string
+	handler func(*brokerMessage)
+}
+
+// Client of the core NATS protocol with headers (PUB, HPUB, SUB, MSG, HMSG,
+// PING, PONG). It works against NATS servers as well as against the
+// embedded broker of donald. Broken connections are established again and
+// the subscriptions are renewed.
+type natsBroker struct {
+	address string
+
+	mutex         sync.Mutex
+	conn          net.Conn
+	subscriptions map[string]*natsSubscription
+	nextSid       int
+	closed        bool
+
+	inFlight chan struct{}
+}
+
+func newNatsBroker(
+	address string,
+) *natsBroker {
+	return &natsBroker{
+		address:       address,
+		subscriptions: map[string]*natsSubscription{},
+		inFlight:      make(chan struct{}, natsMaxInFlightMessages),
+	}
+}
+
+func (b *natsBroker) name() string {
+	return "nats"
+}
+
+// Connects to the server and subscribes again. The mutex must be held.
+func (b *natsBroker) connect() error {
+	conn, err := net.DialTimeout("tcp", b.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+
+	// The server introduces itself first
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := readNatsLine(reader)
+	if err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return errors.New("nats server did not send info")
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	_, err = conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"headers\":true,\"name\":\"" + appName + "\"}\r\nPING\r\n"))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	for _, sub := range b.subscriptions {
+		_, err = conn.Write([]byte(formatNatsSubscribe(sub)))
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	b.conn = conn
+	go b.readLoop(conn, reader)
+	return nil
+}
+
+func (b *natsBroker) publish(
+	subject string,
+	header map[string]string,
+	data []byte,
+) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return errors.New("broker is closed")
+	}
+	if b.conn == nil {
+		err := b.connect()
+		if err != nil {
+			return err
+		}
+	}
+
+	var frame string
+	if len(header) == 0 {
+		frame = "PUB " + subject + " " + strconv.Itoa(len(data)) + "\r\n" + string(data) + "\r\n"
+	} else {
+		headerBlock := formatNatsHeader(header)
+		frame = "HPUB " + subject + " " + strconv.Itoa(len(headerBlock)) + " " + strconv.Itoa(len(headerBlock)+len(data)) + "\r\n" +
+			headerBlock + string(data) + "\r\n"
+	}
+
+	_, err := b.conn.Write([]byte(frame))
+	if err != nil {
+		b.conn.Close()
+		b.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Subscribes to the subject. Subscribers with the same queue share the
+// messages instead of receiving every one of them.
+func (b *natsBroker) subscribe(
+	subject string,
+	queue string,
+	handler func(*brokerMessage),
+) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.nextSid++
+	sub := &natsSubscription{
+		sid:     strconv.Itoa(b.nextSid),
+		subject: subject,
+		queue:   queue,
+		handler: handler,
+	}
+	b.subscriptions[sub.sid] = sub
+
+	if b.conn == nil {
+		err := b.connect()
+		if err != nil {
+			// Retried in the background
+			go b.reconnect()
+		}
+		return err
+	}
+
+	_, err := b.conn.Write([]byte(formatNatsSubscribe(sub)))
+	return err
+}
+
+func (b *natsBroker) close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
+
+func (b *natsBroker) readLoop(
+	conn net.Conn,
+	reader *bufio.Reader,
+) {
+	err := b.readMessages(conn, reader)
+
+	b.mutex.Lock()
+	if b.conn == conn {
+		conn.Close()
+		b.conn = nil
+	}
+	resubscribe := !b.closed && len(b.subscriptions) > 0
+	b.mutex.Unlock()
+
+	if resubscribe {
+		logrus.Warn("Connection to broker is lost: " + err.Error())
+		b.reconnect()
+	}
+}
+
+func (b *natsBroker) readMessages(
+	conn net.Conn,
+	reader *bufio.Reader,
+) error {
+	for {
+		line, err := readNatsLine(reader)
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			b.mutex.Lock()
+			_, err = conn.Write([]byte("PONG\r\n"))
+			b.mutex.Unlock()
+			if err != nil {
+				return err
+			}
+		case "MSG", "HMSG":
+			msg, sid, err := readNatsMessage(reader, fields)
+			if err != nil {
+				return err
+			}
+
+			b.mutex.Lock()
+			sub, ok := b.subscriptions[sid]
+			b.mutex.Unlock()
+			if ok {
+				b.inFlight <- struct{}{}
+				go func() {
+					defer func() { <-b.inFlight }()
+					sub.handler(msg)
+				}()
+			}
+		case "-ERR":
+			logrus.Warn("Broker responded with error: " + line)
+		}
+	}
+}
+
+// Establishes the connection again until it succeeds or the broker is
+// closed.
+func (b *natsBroker) reconnect() {
+	for {
+		time.Sleep(natsReconnectGap)
+
+		b.mutex.Lock()
+		if b.closed || b.conn != nil {
+			b.mutex.Unlock()
+			return
+		}
+		err := b.connect()
+		b.mutex.Unlock()
+
+		if err == nil {
+			logrus.Info("Connection to broker is established again.")
+			return
+		}
+	}
+}
+
+func formatNatsSubscribe(
+	sub *natsSubscription,
+) string {
+	if sub.queue == "" {
+		return "SUB " + sub.subject + " " + sub.sid + "\r\n"
+	}
+	return "SUB " + sub.subject + " " + sub.queue + " " + sub.sid + "\r\n"
+}
+
+func formatNatsHeader(
+	header map[string]string,
+) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(natsHeaderLine + "\r\n")
+	for _, key := range keys {
+		sb.WriteString(key + ": " + header[key] + "\r\n")
+	}
+	sb.WriteString("\r\n")
+	return sb.String()
+}
+
+func parseNatsHeader(
+	headerBlock []byte,
+) map[string]string {
+	header := map[string]string{}
+	lines := strings.Split(string(headerBlock), "\r\n")
+	for _, line := range lines[1:] {
+		key, value, found := strings.Cut(line, ":")
+		if found {
+			header[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return header
+}
+
+// Reads the payload of a message whose control line is given:
+// MSG <subject> <sid> [reply-to] <#bytes>
+// HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+func readNatsMessage(
+	reader *bufio.Reader,
+	fields []string,
+) (
+	*brokerMessage,
+	string,
+	error,
+) {
+	isHeaderMessage := strings.ToUpper(fields[0]) == "HMSG"
+	minFields := 4
+	if isHeaderMessage {
+		minFields = 5
+	}
+	if len(fields) < minFields {
+		return nil, "", errors.New("invalid message: " + strings.Join(fields, " "))
+	}
+
+	headerSize := 0
+	totalSize, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	if totalSize < 0 {
+		return nil, "", errors.New("invalid message size")
+	}
+	if isHeaderMessage {
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+		if err != nil || headerSize < 0 || headerSize > totalSize {
+			return nil, "", errors.New("invalid header size")
+		}
+	}
+
+	payload := make([]byte, totalSize+2)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	msg := &brokerMessage{
+		subject: fields[1],
+		header:  map[string]string{},
+		data:    payload[headerSize:totalSize],
+	}
+	if isHeaderMessage {
+		msg.header = parseNatsHeader(payload[:headerSize])
+	}
+	return msg, fields[2], nil
+}
+
+func readNatsLine(
+	reader *bufio.Reader,
+) (
+	string,
+	error,
+) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
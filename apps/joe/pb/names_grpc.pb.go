@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: names.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// NameServiceClient is the client API for NameService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NameServiceClient interface {
+	// Lists all names or the name with the given id.
+	ListNames(ctx context.Context, in *ListNamesRequest, opts ...grpc.CallOption) (*ListNamesResponse, error)
+	// Deletes all names or the name with the given id.
+	DeleteNames(ctx context.Context, in *DeleteNamesRequest, opts ...grpc.CallOption) (*DeleteNamesResponse, error)
+	// Stores a new name.
+	CreateName(ctx context.Context, in *CreateNameRequest, opts ...grpc.CallOption) (*CreateNameResponse, error)
+}
+
+type nameServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNameServiceClient(cc grpc.ClientConnInterface) NameServiceClient {
+	return &nameServiceClient{cc}
+}
+
+func (c *nameServiceClient) ListNames(ctx context.Context, in *ListNamesRequest, opts ...grpc.CallOption) (*ListNamesResponse, error) {
+	out := new(ListNamesResponse)
+	err := c.cc.Invoke(ctx, "/donald.v1.NameService/ListNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameServiceClient) DeleteNames(ctx context.Context, in *DeleteNamesRequest, opts ...grpc.CallOption) (*DeleteNamesResponse, error) {
+	out := new(DeleteNamesResponse)
+	err := c.cc.Invoke(ctx, "/donald.v1.NameService/DeleteNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameServiceClient) CreateName(ctx context.Context, in *CreateNameRequest, opts ...grpc.CallOption) (*CreateNameResponse, error) {
+	out := new(CreateNameResponse)
+	err := c.cc.Invoke(ctx, "/donald.v1.NameService/CreateName", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NameServiceServer is the server API for NameService service.
+// All implementations must embed UnimplementedNameServiceServer
+// for forward compatibility
+type NameServiceServer interface {
+	// Lists all names or the name with the given id.
+	ListNames(context.Context, *ListNamesRequest) (*ListNamesResponse, error)
+	// Deletes all names or the name with the given id.
+	DeleteNames(context.Context, *DeleteNamesRequest) (*DeleteNamesResponse, error)
+	// Stores a new name.
+	CreateName(context.Context, *CreateNameRequest) (*CreateNameResponse, error)
+	mustEmbedUnimplementedNameServiceServer()
+}
+
+// UnimplementedNameServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedNameServiceServer struct {
+}
+
+func (UnimplementedNameServiceServer) ListNames(context.Context, *ListNamesRequest) (*ListNamesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNames not implemented")
+}
+func (UnimplementedNameServiceServer) DeleteNames(context.Context, *DeleteNamesRequest) (*DeleteNamesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNames not implemented")
+}
+func (UnimplementedNameServiceServer) CreateName(context.Context, *CreateNameRequest) (*CreateNameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateName not implemented")
+}
+func (UnimplementedNameServiceServer) mustEmbedUnimplementedNameServiceServer() {}
+
+// UnsafeNameServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NameServiceServer will
+// result in compilation errors.
+type UnsafeNameServiceServer interface {
+	mustEmbedUnimplementedNameServiceServer()
+}
+
+func RegisterNameServiceServer(s grpc.ServiceRegistrar, srv NameServiceServer) {
+	s.RegisterService(&NameService_ServiceDesc, srv)
+}
+
+func _NameService_ListNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameServiceServer).ListNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/donald.v1.NameService/ListNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameServiceServer).ListNames(ctx, req.(*ListNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NameService_DeleteNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameServiceServer).DeleteNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/donald.v1.NameService/DeleteNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameServiceServer).DeleteNames(ctx, req.(*DeleteNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NameService_CreateName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameServiceServer).CreateName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/donald.v1.NameService/CreateName",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameServiceServer).CreateName(ctx, req.(*CreateNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// NameService_ServiceDesc is the grpc.ServiceDesc for NameService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NameService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "donald.v1.NameService",
+	HandlerType: (*NameServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListNames",
+			Handler:    _NameService_ListNames_Handler,
+		},
+		{
+			MethodName: "DeleteNames",
+			Handler:    _NameService_DeleteNames_Handler,
+		},
+		{
+			MethodName: "CreateName",
+			Handler:    _NameService_CreateName_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "names.proto",
+}
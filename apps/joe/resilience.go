@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Error code of donald when it lost the connection to its database
+	errorCodeDatabaseConnectionLost = "database_connection_lost"
+
+	defaultDonaldRequestTimeout = 30 * time.Second
+)
+
+var (
+	donaldRequestTimeout    time.Duration
+	donaldMaxRetries        int
+	donaldRetryBackoff      time.Duration
+	donaldRetryBackoffLimit time.Duration
+
+	circuitBreakerFailureThreshold int
+	circuitBreakerOpenDuration     time.Duration
+
+	donaldCircuitBreaker *circuitBreaker
+
+	errCircuitOpen = errors.New("circuit breaker for donald is open")
+)
+
+type circuitBreakerState int
+
+const (
+	circuitBreakerClosed circuitBreakerState = iota
+	circuitBreakerHalfOpen
+	circuitBreakerOpen
+)
+
+func (s circuitBreakerState) String() string {
+	switch s {
+	case circuitBreakerOpen:
+		return "open"
+	case circuitBreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Stops calling a failing dependency after a number of consecutive failures.
+// After the open duration, a single trial call is let through which decides
+// whether the breaker is closed again or stays open.
+type circuitBreaker struct {
+	mutex               sync.Mutex
+	failureThreshold    int
+	openDuration        time.Duration
+	state               circuitBreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+}
+
+func newCircuitBreaker(
+	failureThreshold int,
+	openDuration time.Duration,
+) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		state:            circuitBreakerClosed,
+	}
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Breaker is disabled
+	if b.failureThreshold <= 0 {
+		return true
+	}
+
+	switch b.state {
+	case circuitBreakerOpen:
+		if time.Since(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.setState(circuitBreakerHalfOpen)
+		b.trialInFlight = true
+		return true
+	case circuitBreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) record(
+	success bool,
+) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.trialInFlight = false
+	if success {
+		b.setState(circuitBreakerClosed)
+		b.consecutiveFailures = 0
+		return
+	}
+
+	b.consecutiveFailures++
+	if b.state == circuitBreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.setState(circuitBreakerOpen)
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *circuitBreaker) setState(
+	state circuitBreakerState,
+) {
+	if b.state != state {
+		logrus.Warn("Circuit breaker for donald is " + state.String() + ".")
+	}
+	b.state = state
+}
+
+func (b *circuitBreaker) currentState() circuitBreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+type resendCountKey struct{}
+
+// Round tripper which is wrapped by the otelhttp transport. It marks every
+// client span with the number of the attempt it belongs to.
+type resendCountTransport struct {
+	base http.RoundTripper
+}
+
+func (t *resendCountTransport) RoundTrip(
+	req *http.Request,
+) (
+	*http.Response,
+	error,
+) {
+	if resendCount, ok := req.Context().Value(resendCountKey{}).(int); ok && resendCount > 0 {
+		span := trace.SpanFromContext(req.Context())
+		span.SetAttributes(attribute.Int("http.resend_count", resendCount))
+	}
+	return t.base.RoundTrip(req)
+}
+
+func withResendCount(
+	ctx context.Context,
+	resendCount int,
+) context.Context {
+	return context.WithValue(ctx, resendCountKey{}, resendCount)
+}
+
+func isIdempotentMethod(
+	httpMethod string,
+) bool {
+	switch httpMethod {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Tells whether a failed call may succeed when it is retried. Donald is only
+// asked again when it is temporarily unable to respond or lost its database.
+// Calls without any response (reset connections, timed out attempts) carry
+// no error of donald and are retried as well.
+func isRetryableError(
+	err error,
+) bool {
+	var dErr *donaldError
+	if !errors.As(err, &dErr) {
+		return true
+	}
+
+	switch dErr.statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return dErr.response.Code == errorCodeDatabaseConnectionLost
+	}
+}
+
+// Calculates the exponential backoff with full jitter for the given retry.
+func getRetryBackoff(
+	retry int,
+) time.Duration {
+	// Retries are not delayed without a backoff
+	if donaldRetryBackoff <= 0 {
+		return 0
+	}
+
+	// Large retries overflow the shift and are capped by the limit as well
+	backoff := donaldRetryBackoff << retry
+	if donaldRetryBackoffLimit > 0 && (backoff > donaldRetryBackoffLimit || backoff <= 0) {
+		backoff = donaldRetryBackoffLimit
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+func waitForRetry(
+	ctx context.Context,
+	retry int,
+) error {
+	timer := time.NewTimer(getRetryBackoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
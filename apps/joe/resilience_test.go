@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{
+			name:      "no response",
+			err:       errors.New("connection reset by peer"),
+			retryable: true,
+		},
+		{
+			name:      "bad gateway",
+			err:       &donaldError{statusCode: http.StatusBadGateway},
+			retryable: true,
+		},
+		{
+			name:      "service unavailable",
+			err:       &donaldError{statusCode: http.StatusServiceUnavailable},
+			retryable: true,
+		},
+		{
+			name:      "gateway timeout",
+			err:       &donaldError{statusCode: http.StatusGatewayTimeout},
+			retryable: true,
+		},
+		{
+			name: "database connection lost",
+			err: &donaldError{
+				statusCode: http.StatusInternalServerError,
+				response:   errorResponse{Code: errorCodeDatabaseConnectionLost},
+			},
+			retryable: true,
+		},
+		{
+			name: "internal server error",
+			err: &donaldError{
+				statusCode: http.StatusInternalServerError,
+				response:   errorResponse{Code: "table_does_not_exist"},
+			},
+			retryable: false,
+		},
+		{
+			name:      "bad request",
+			err:       &donaldError{statusCode: http.StatusBadRequest},
+			retryable: false,
+		},
+		{
+			name:      "unauthorized",
+			err:       &donaldError{statusCode: http.StatusUnauthorized},
+			retryable: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if retryable := isRetryableError(test.err); retryable != test.retryable {
+				t.Fatalf("expected retryable %v, got %v", test.retryable, retryable)
+			}
+		})
+	}
+}
+
+func TestGetRetryBackoff(t *testing.T) {
+	previousBackoff, previousLimit := donaldRetryBackoff, donaldRetryBackoffLimit
+	t.Cleanup(func() {
+		donaldRetryBackoff, donaldRetryBackoffLimit = previousBackoff, previousLimit
+	})
+	donaldRetryBackoff = 10 * time.Millisecond
+	donaldRetryBackoffLimit = 50 * time.Millisecond
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{retry: 0, max: 10 * time.Millisecond},
+		{retry: 1, max: 20 * time.Millisecond},
+		{retry: 2, max: 40 * time.Millisecond},
+		{retry: 3, max: 50 * time.Millisecond},
+		// Shifting overflows, the limit still applies
+		{retry: 70, max: 50 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			backoff := getRetryBackoff(test.retry)
+			if backoff < 0 || backoff >= test.max {
+				t.Fatalf("expected the backoff of retry %d within [0, %s), got %s", test.retry, test.max, backoff)
+			}
+		}
+	}
+
+	// Without a backoff, retries are not delayed
+	donaldRetryBackoff = 0
+	if backoff := getRetryBackoff(1); backoff != 0 {
+		t.Fatalf("expected no backoff, got %s", backoff)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, 50*time.Millisecond)
+
+	// Opens after the consecutive failures only
+	b.record(false)
+	b.record(true)
+	b.record(false)
+	if !b.allow() || b.currentState() != circuitBreakerClosed {
+		t.Fatalf("expected the breaker to be closed, got %s", b.currentState())
+	}
+	b.record(false)
+	if b.allow() || b.currentState() != circuitBreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", b.currentState())
+	}
+
+	// Lets a single trial through after the open duration
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() || b.currentState() != circuitBreakerHalfOpen {
+		t.Fatalf("expected the trial call to be allowed, got %s", b.currentState())
+	}
+	if b.allow() {
+		t.Fatal("expected only a single trial call")
+	}
+
+	// A failed trial opens the breaker again
+	b.record(false)
+	if b.allow() || b.currentState() != circuitBreakerOpen {
+		t.Fatalf("expected the breaker to be open again, got %s", b.currentState())
+	}
+
+	// A successful trial closes it
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("expected the trial call to be allowed")
+	}
+	b.record(true)
+	if !b.allow() || b.currentState() != circuitBreakerClosed {
+		t.Fatalf("expected the breaker to be closed, got %s", b.currentState())
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.record(false)
+	}
+	if !b.allow() || b.currentState() != circuitBreakerClosed {
+		t.Fatalf("expected the disabled breaker to let every call through, got %s", b.currentState())
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	otelruntime "go.opentelemetry.io/contrib/instrumentation/runtime"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// Collects the metrics of the Go runtime (goroutines, GC pauses, heap, ...)
+// and of the host (CPU time of the process, memory, network, ...) with the
+// given provider.
+func startRuntimeInstrumentation(
+	mp *sdkmetric.MeterProvider,
+) {
+	err := otelruntime.Start(
+		otelruntime.WithMeterProvider(mp),
+		otelruntime.WithMinimumReadMemStatsInterval(time.Second),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	err = host.Start(host.WithMeterProvider(mp))
+	if err != nil {
+		panic(err)
+	}
+}
@@ -3,80 +3,120 @@ package main
 import (
 	"errors"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 func handler(
 	w http.ResponseWriter,
 	r *http.Request,
+	scope *requestScope,
 ) {
 
-	// Get server span
-	parentSpan := trace.SpanFromContext(r.Context())
-	defer parentSpan.End()
-
-	// Get caller user
-	user := r.Header.Get("X-User-ID")
-	if user == "" {
-		user = "_anonymous_"
-	}
+	// Get authenticated caller user
+	user := getUser(r)
 
 	log(logrus.InfoLevel, r.Context(), user, "Handler is triggered")
 
-	err := performPreprocessing(r, &parentSpan, user)
+	// Track the outcome of the request for the abuse detection
+	recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	w = recorder
+	defer func() {
+		detectAbuse(r.Context(), user, recorder.statusCode)
+	}()
+
+	if !checkUserAccess(w, r, scope, user) {
+		return
+	}
+
+	query, err := performPreprocessing(r, scope, user)
 	if err != nil {
-		createHttpResponse(&w, http.StatusBadRequest, []byte("Fail"), &parentSpan)
+		if fieldErrors := getValidationErrors(err); fieldErrors != nil {
+			createValidationErrorResponse(&w, fieldErrors, scope)
+			return
+		}
+		createErrorResponse(&w, http.StatusBadRequest, errorCodeInvalidDataFormat, "Provided data format is invalid and cannot be processed.", scope)
+		return
+	}
+
+	// Publish deletions as commands to donald
+	if donaldAsyncCommands && r.Method == http.MethodDelete {
+		err = publishDonaldCommand(r.Context(), user, commandTypeDelete, scope.pathParams["id"], "", getRequestParams(query))
+		if err != nil {
+			createErrorResponse(&w, http.StatusServiceUnavailable, errorCodeMessagingUnavailable, "Command could not be published.", scope)
+			return
+		}
+		createHttpResponse(&w, http.StatusAccepted, []byte("Accepted"))
 		return
 	}
 
 	// Perform request to Donald service
-	err = performRequestToDonald(r, user)
+	err = performRequestToDonald(r, user, query)
 	if err != nil {
-		createHttpResponse(&w, http.StatusInternalServerError, []byte("Fail"), &parentSpan)
+		statusCode, code, msg := getDonaldErrorResponse(err)
+		createErrorResponse(&w, statusCode, code, msg, scope)
 		return
 	}
 
-	createHttpResponse(&w, http.StatusOK, []byte("Success"), &parentSpan)
+	createHttpResponse(&w, http.StatusOK, []byte("Success"))
 }
 
 func performRequestToDonald(
 	r *http.Request,
 	user string,
+	query url.Values,
 ) error {
-	// Add request parameters
+	// Make the call to the same route on donald
+	return performDonaldCall(r.Context(), r.Method, user, r.URL.Path, getRequestParams(query))
+}
+
+// Flattens the normalized query parameters.
+func getRequestParams(
+	query url.Values,
+) map[string]string {
 	reqParams := map[string]string{}
-	for k, v := range r.URL.Query() {
+	for k, v := range query {
 		reqParams[k] = v[0]
 	}
-	// Make the call
-	return performHttpCall(r.Context(), r.Method, user, reqParams)
+	return reqParams
 }
 
 func performPreprocessing(
 	r *http.Request,
-	parentSpan *trace.Span,
+	scope *requestScope,
 	user string,
-) error {
+) (
+	url.Values,
+	error,
+) {
 
 	log(logrus.InfoLevel, r.Context(), user, "Preprocessing...")
+
+	ctx := r.Context()
+	var processingSpan trace.Span
 	if considerPreprocessingSpans {
-		ctx, processingSpan := (*parentSpan).TracerProvider().
-			Tracer(appName).
-			Start(
-				r.Context(),
-				"preprocessing",
-				trace.WithSpanKind(trace.SpanKindInternal),
-			)
+		ctx, processingSpan = scope.startInternalSpan(ctx, "preprocessing")
 		defer processingSpan.End()
+	}
 
-		err := produceException(r)
-		if err != nil {
+	// Validate and normalize the request
+	query, fieldErrors := validateRequest(r, scope)
+	if len(fieldErrors) > 0 {
+		log(logrus.WarnLevel, ctx, user, "Request is invalid: "+strconv.Itoa(len(fieldErrors))+" field(s) failed validation.")
+		recordValidationFailures(ctx, r, fieldErrors)
+		recordPreprocessingFailure(ctx, r.Method, "invalid_request")
+		return nil, &validationError{fieldErrors: fieldErrors}
+	}
 
+	err := produceException(query)
+	if err != nil {
+		recordPreprocessingFailure(ctx, r.Method, "invalid_data_format")
+		if considerPreprocessingSpans {
 			msg := "Provided data format is invalid and cannot be processed."
 			log(logrus.ErrorLevel, ctx, user, msg)
 
@@ -89,37 +129,32 @@ func performPreprocessing(
 				attribute.String("exception.stacktrace", string(stackSlice[0:s])),
 			}
 			processingSpan.SetAttributes(attrs...)
-			return err
+			setSpanError(processingSpan, msg)
 		}
-		log(logrus.InfoLevel, r.Context(), user, "Preprocessing is completed.")
-		return nil
+		return nil, err
 	}
 
-	err := produceException(r)
-	return err
+	log(logrus.InfoLevel, r.Context(), user, "Preprocessing is completed.")
+	return query, nil
 }
 
 func produceException(
-	r *http.Request,
+	query url.Values,
 ) error {
-	preprocessingException := r.URL.Query().Get("preprocessingException")
+	preprocessingException := query.Get("preprocessingException")
 	if preprocessingException == "true" {
 		return errors.New("preprocessing failed")
 	}
 	return nil
 }
 
+// Writes the response. The HTTP status code and the span status of the
+// server span are set by the otelhttp handler accordingly.
 func createHttpResponse(
 	w *http.ResponseWriter,
 	statusCode int,
 	body []byte,
-	serverSpan *trace.Span,
 ) {
 	(*w).WriteHeader(statusCode)
 	(*w).Write(body)
-
-	attrs := []attribute.KeyValue{
-		semconv.HTTPStatusCode(statusCode),
-	}
-	(*serverSpan).SetAttributes(attrs...)
 }
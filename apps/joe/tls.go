@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	stdlog "log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tlsCertFile       string
+	tlsKeyFile        string
+	tlsClientCaFile   string
+	tlsReloadInterval time.Duration
+
+	donaldTls           bool
+	donaldTlsCaFile     string
+	donaldTlsCertFile   string
+	donaldTlsKeyFile    string
+	donaldTlsServerName string
+)
+
+// Keeps a certificate and a CA pool in sync with their files so that rotated
+// certificates are picked up without a restart.
+type tlsReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	interval time.Duration
+
+	mutex     sync.RWMutex
+	cert      *tls.Certificate
+	caPool    *x509.CertPool
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+func newTlsReloader(
+	certFile string,
+	keyFile string,
+	caFile string,
+	interval time.Duration,
+) (
+	*tlsReloader,
+	error,
+) {
+	r := &tlsReloader{
+		certFile:  certFile,
+		keyFile:   keyFile,
+		caFile:    caFile,
+		interval:  interval,
+		modTimes:  map[string]time.Time{},
+		lastCheck: time.Now(),
+	}
+	err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reads the files again when any of them has been modified since the last
+// load.
+func (r *tlsReloader) load() error {
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	r.mutex.RLock()
+	changed := len(modTimes) != len(r.modTimes)
+	for file, modTime := range modTimes {
+		if !r.modTimes[file].Equal(modTime) {
+			changed = true
+		}
+	}
+	r.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	var cert *tls.Certificate
+	if r.certFile != "" && r.keyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+
+	var caPool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in " + r.caFile)
+		}
+	}
+
+	r.mutex.Lock()
+	r.cert = cert
+	r.caPool = caPool
+	r.modTimes = modTimes
+	r.mutex.Unlock()
+
+	logrus.Info("TLS certificates are loaded.")
+	return nil
+}
+
+// Returns the current certificate and CA pool. The files are checked for
+// changes at most once per interval.
+func (r *tlsReloader) current() (
+	*tls.Certificate,
+	*x509.CertPool,
+) {
+	if r.interval > 0 {
+		r.mutex.Lock()
+		due := time.Since(r.lastCheck) >= r.interval
+		if due {
+			r.lastCheck = time.Now()
+		}
+		r.mutex.Unlock()
+
+		if due {
+			err := r.load()
+			if err != nil {
+				logrus.Warn("TLS certificates could not be reloaded: " + err.Error())
+			}
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, r.caPool
+}
+
+// Creates the server side TLS config. Without a certificate, nil is returned
+// and the server falls back to plain HTTP. With a client CA, the callers have
+// to present a certificate which is signed by it (mutual TLS).
+func createServerTlsConfig() *tls.Config {
+	if tlsCertFile == "" || tlsKeyFile == "" {
+		return nil
+	}
+
+	reloader, err := newTlsReloader(tlsCertFile, tlsKeyFile, tlsClientCaFile, tlsReloadInterval)
+	if err != nil {
+		panic(err)
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := reloader.current()
+			return cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, caPool := reloader.current()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if caPool != nil {
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				config.ClientCAs = caPool
+			}
+			return config, nil
+		},
+	}
+}
+
+// Serves the handler over HTTPS when a certificate is configured and over
+// plain HTTP otherwise.
+func listenAndServe(
+	handler http.Handler,
+) error {
+	server := &http.Server{
+		Addr:    ":" + appPort,
+		Handler: handler,
+	}
+
+	tlsConfig := createServerTlsConfig()
+	if tlsConfig == nil {
+		return server.ListenAndServe()
+	}
+
+	server.TLSConfig = tlsConfig
+	server.ErrorLog = stdlog.New(&handshakeErrorWriter{}, "", 0)
+	return server.ListenAndServeTLS("", "")
+}
+
+// Reports the TLS handshake errors which are logged by the HTTP server as
+// failed spans. They occur before any handler is run and would not show up
+// in the traces otherwise.
+type handshakeErrorWriter struct{}
+
+func (w *handshakeErrorWriter) Write(
+	p []byte,
+) (
+	int,
+	error,
+) {
+	msg := strings.TrimSpace(string(p))
+	logrus.Warn(msg)
+
+	// Message format: "http: TLS handshake error from <addr>: <error>"
+	_, rest, found := strings.Cut(msg, "TLS handshake error from ")
+	if !found {
+		return len(p), nil
+	}
+	peerAddr, reason, _ := strings.Cut(rest, ": ")
+
+	_, span := otel.GetTracerProvider().Tracer(appName).Start(
+		context.Background(),
+		"TLS handshake",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("net.protocol.name", "tls"),
+			attribute.String("net.sock.peer.addr", peerAddr),
+		),
+	)
+	setSpanError(span, reason)
+	span.End()
+
+	return len(p), nil
+}
+
+// Creates the client side TLS config for the calls to donald. The server
+// certificate is verified against the reloaded CA pool (or the system roots
+// without a CA file) and the client certificate is presented when donald asks
+// for it (mutual TLS).
+func createClientTlsConfig() *tls.Config {
+	if !donaldTls {
+		return nil
+	}
+
+	reloader, err := newTlsReloader(donaldTlsCertFile, donaldTlsKeyFile, donaldTlsCaFile, tlsReloadInterval)
+	if err != nil {
+		panic(err)
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: donaldTlsServerName,
+		// The default verification cannot pick up a rotated CA pool, it is
+		// done in VerifyConnection instead.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(connState tls.ConnectionState) error {
+			_, caPool := reloader.current()
+			return verifyServerCertificate(connState, caPool)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := reloader.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+	}
+}
+
+func verifyServerCertificate(
+	connState tls.ConnectionState,
+	caPool *x509.CertPool,
+) error {
+	if len(connState.PeerCertificates) == 0 {
+		return errors.New("tls: server did not provide a certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       connState.ServerName,
+		Roots:         caPool,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range connState.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := connState.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// Records the transport of the calls to donald on the client span and marks
+// the span as failed when the TLS handshake did not succeed.
+type transportInfoTransport struct {
+	base http.RoundTripper
+}
+
+func (t *transportInfoTransport) RoundTrip(
+	req *http.Request,
+) (
+	*http.Response,
+	error,
+) {
+	span := trace.SpanFromContext(req.Context())
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		if isTlsError(err) {
+			span.AddEvent("tls.handshake.failure", trace.WithAttributes(
+				attribute.String("reason", err.Error()),
+			))
+			setSpanError(span, err.Error())
+		}
+		return nil, err
+	}
+
+	span.SetAttributes(getTransportAttributes(res.ProtoMajor, res.ProtoMinor, res.TLS)...)
+	return res, nil
+}
+
+func isTlsError(
+	err error,
+) bool {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	return errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certificateInvalidErr) ||
+		errors.As(err, &recordHeaderErr) ||
+		strings.Contains(err.Error(), "tls: ")
+}
+
+func getDonaldScheme() string {
+	if donaldTls {
+		return "https"
+	}
+	return "http"
+}
+
+func getTlsVersionName(
+	version uint16,
+) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	default:
+		return "unknown"
+	}
+}
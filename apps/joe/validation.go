@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errorCodeInvalidRequest = "invalid_request"
+
+	maxRequestBodySize = 1 << 20
+)
+
+type fieldKind int
+
+const (
+	fieldKindBool fieldKind = iota
+	fieldKindInt
+	fieldKindString
+)
+
+type fieldSchema struct {
+	name      string
+	kind      fieldKind
+	required  bool
+	minValue  int
+	maxLength int
+}
+
+// Expected shape of the path parameters, query parameters and JSON body of
+// the requests to a route.
+type requestSchema struct {
+	pathParams  []fieldSchema
+	queryParams []fieldSchema
+	bodyFields  []fieldSchema
+}
+
+// Validation failure of a single field.
+type fieldError struct {
+	Field    string `json:"field,omitempty"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+	reason   string
+}
+
+type validationError struct {
+	fieldErrors []fieldError
+}
+
+func (e *validationError) Error() string {
+	return "request validation failed for " + strconv.Itoa(len(e.fieldErrors)) + " fields"
+}
+
+var (
+	apiQueryParams = []fieldSchema{
+		{name: "databaseConnectionError", kind: fieldKindBool},
+		{name: "tableDoesNotExistError", kind: fieldKindBool},
+		{name: "preprocessingException", kind: fieldKindBool},
+		{name: "schemaNotFoundInCacheWarning", kind: fieldKindBool},
+		{name: "slowQuery", kind: fieldKindBool},
+		{name: "lockContention", kind: fieldKindBool},
+		{name: "deadlock", kind: fieldKindBool},
+	}
+
+	requestSchemas = map[string]requestSchema{
+		"/api": {
+			queryParams: apiQueryParams,
+		},
+		"/api/{id}": {
+			pathParams: []fieldSchema{
+				{name: "id", kind: fieldKindInt, required: true, minValue: 1},
+			},
+			queryParams: apiQueryParams,
+		},
+	}
+)
+
+// Validates the request against the schema of its route and returns the
+// normalized query parameters.
+func validateRequest(
+	r *http.Request,
+	scope *requestScope,
+) (
+	url.Values,
+	[]fieldError,
+) {
+	schema := requestSchemas[scope.route]
+	fieldErrors := []fieldError{}
+
+	// Path parameters
+	for _, field := range schema.pathParams {
+		value, ok := scope.pathParams[field.name]
+		_, fieldErr := validateField(field, "path", value, ok)
+		if fieldErr != nil {
+			fieldErrors = append(fieldErrors, *fieldErr)
+		}
+	}
+
+	// Query parameters
+	query := r.URL.Query()
+	normalizedQuery := url.Values{}
+	for _, field := range schema.queryParams {
+		values, ok := query[field.name]
+		if ok && len(values) > 1 {
+			fieldErrors = append(fieldErrors, newFieldError(field.name, "query", "duplicate", "Parameter is provided more than once."))
+			continue
+		}
+
+		value := ""
+		if ok {
+			value = values[0]
+		}
+		normalizedValue, fieldErr := validateField(field, "query", value, ok)
+		if fieldErr != nil {
+			fieldErrors = append(fieldErrors, *fieldErr)
+			continue
+		}
+		if ok {
+			normalizedQuery.Set(field.name, normalizedValue)
+		}
+	}
+	for _, name := range getSortedKeys(query) {
+		if findField(schema.queryParams, name) == nil {
+			fieldErrors = append(fieldErrors, newFieldError(name, "query", "unknown", "Parameter is not supported."))
+		}
+	}
+
+	// Body
+	fieldErrors = append(fieldErrors, validateBody(r, schema.bodyFields)...)
+
+	return normalizedQuery, fieldErrors
+}
+
+func validateBody(
+	r *http.Request,
+	fields []fieldSchema,
+) []fieldError {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+	if err != nil {
+		return []fieldError{newFieldError("", "body", "unreadable", "Body could not be read.")}
+	}
+	if len(body) > maxRequestBodySize {
+		return []fieldError{newFieldError("", "body", "too_large", "Body exceeds "+strconv.Itoa(maxRequestBodySize)+" bytes.")}
+	}
+
+	fieldErrors := []fieldError{}
+	payload := map[string]interface{}{}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		err = json.Unmarshal(body, &payload)
+		if err != nil {
+			return []fieldError{newFieldError("", "body", "malformed", "Body is not a valid JSON object.")}
+		}
+	}
+
+	for _, field := range fields {
+		raw, ok := payload[field.name]
+		value := ""
+		if ok {
+			value = stringifyJsonValue(raw)
+		}
+		_, fieldErr := validateField(field, "body", value, ok)
+		if fieldErr != nil {
+			fieldErrors = append(fieldErrors, *fieldErr)
+		}
+	}
+	for _, name := range getSortedKeys(payload) {
+		if findField(fields, name) == nil {
+			fieldErrors = append(fieldErrors, newFieldError(name, "body", "unknown", "Field is not supported."))
+		}
+	}
+	return fieldErrors
+}
+
+// Checks a single value against its schema and returns its normalized form.
+func validateField(
+	field fieldSchema,
+	location string,
+	value string,
+	present bool,
+) (
+	string,
+	*fieldError,
+) {
+	if !present {
+		if field.required {
+			fieldErr := newFieldError(field.name, location, "missing", "Value is required.")
+			return "", &fieldErr
+		}
+		return "", nil
+	}
+
+	value = strings.TrimSpace(value)
+	switch field.kind {
+	case fieldKindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			fieldErr := newFieldError(field.name, location, "invalid_type", "Value must be a boolean.")
+			return "", &fieldErr
+		}
+		return strconv.FormatBool(b), nil
+	case fieldKindInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			fieldErr := newFieldError(field.name, location, "invalid_type", "Value must be an integer.")
+			return "", &fieldErr
+		}
+		if i < field.minValue {
+			fieldErr := newFieldError(field.name, location, "out_of_range", "Value must be at least "+strconv.Itoa(field.minValue)+".")
+			return "", &fieldErr
+		}
+		return strconv.Itoa(i), nil
+	default:
+		if field.maxLength > 0 && len(value) > field.maxLength {
+			fieldErr := newFieldError(field.name, location, "too_long", "Value must not exceed "+strconv.Itoa(field.maxLength)+" characters.")
+			return "", &fieldErr
+		}
+		return value, nil
+	}
+}
+
+func newFieldError(
+	field string,
+	location string,
+	reason string,
+	message string,
+) fieldError {
+	return fieldError{
+		Field:    field,
+		Location: location,
+		Message:  message,
+		reason:   reason,
+	}
+}
+
+func findField(
+	fields []fieldSchema,
+	name string,
+) *fieldSchema {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func getSortedKeys[V any](
+	m map[string]V,
+) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringifyJsonValue(
+	raw interface{},
+) string {
+	switch v := raw.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// Adds a span event and increases the failure counter for every invalid
+// field. Unknown fields are not used as metric attribute to keep the
+// cardinality low.
+func recordValidationFailures(
+	ctx context.Context,
+	r *http.Request,
+	fieldErrors []fieldError,
+) {
+	span := trace.SpanFromContext(ctx)
+	for _, fieldErr := range fieldErrors {
+		span.AddEvent("validation.failure", trace.WithAttributes(
+			attribute.String("field.name", fieldErr.Field),
+			attribute.String("field.location", fieldErr.Location),
+			attribute.String("reason", fieldErr.reason),
+			attribute.String("message", fieldErr.Message),
+		))
+
+		metricField := fieldErr.Field
+		if fieldErr.reason == "unknown" {
+			metricField = "_unknown_"
+		}
+		validationFailures.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("field.name", metricField),
+				attribute.String("field.location", fieldErr.Location),
+				attribute.String("reason", fieldErr.reason),
+			),
+		)
+	}
+}
+
+func getValidationErrors(
+	err error,
+) []fieldError {
+	var vErr *validationError
+	if errors.As(err, &vErr) {
+		return vErr.fieldErrors
+	}
+	return nil
+}
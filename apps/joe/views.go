@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+const (
+	defaultExponentialHistogramMaxSize  = 160
+	defaultExponentialHistogramMaxScale = 20
+)
+
+var (
+	metricViewsFile string
+)
+
+// Views of the file in the format {"views": [...]}.
+type metricViewsConfig struct {
+	Views []metricViewConfig `json:"views"`
+}
+
+// Changes how the measurements of the matching instruments are aggregated
+// and exported. Instruments are matched by their name which supports the
+// wildcards * and ?, and optionally by the name of their scope.
+type metricViewConfig struct {
+	Instrument         string                    `json:"instrument"`
+	Scope              string                    `json:"scope"`
+	Rename             string                    `json:"rename"`
+	Description        string                    `json:"description"`
+	Boundaries         []float64                 `json:"boundaries"`
+	ExponentialBuckets *exponentialBucketsConfig `json:"exponentialBuckets"`
+	NoMinMax           bool                      `json:"noMinMax"`
+	Drop               bool                      `json:"drop"`
+	AllowAttributes    []string                  `json:"allowAttributes"`
+	DropAttributes     []string                  `json:"dropAttributes"`
+}
+
+// Base2 exponential histogram whose buckets follow the recorded values. The
+// SDK defaults are used for the unset limits.
+type exponentialBucketsConfig struct {
+	MaxSize  int32  `json:"maxSize"`
+	MaxScale *int32 `json:"maxScale"`
+}
+
+// Loads the views from the configured file. Without a file, the SDK defaults
+// are used for all instruments.
+func loadMetricViews() []sdkmetric.View {
+	if metricViewsFile == "" {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(metricViewsFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var config metricViewsConfig
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		panic(err)
+	}
+
+	views := make([]sdkmetric.View, 0, len(config.Views))
+	for i, viewConfig := range config.Views {
+		view, err := newMetricView(viewConfig)
+		if err != nil {
+			panic("invalid metric view " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		views = append(views, view)
+	}
+
+	logrus.Info(strconv.Itoa(len(views)) + " metric views are loaded from " + metricViewsFile + ".")
+	return views
+}
+
+func newMetricView(
+	config metricViewConfig,
+) (
+	sdkmetric.View,
+	error,
+) {
+	if config.Instrument == "" {
+		return nil, errors.New("instrument is missing")
+	}
+	if config.Rename != "" && strings.ContainsAny(config.Instrument, "*?") {
+		return nil, errors.New("instruments which are matched with wildcards cannot be renamed")
+	}
+	if len(config.AllowAttributes) > 0 && len(config.DropAttributes) > 0 {
+		return nil, errors.New("attributes are either allowed or dropped")
+	}
+
+	agg, err := getMetricViewAggregation(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdkmetric.NewView(
+		sdkmetric.Instrument{
+			Name:  config.Instrument,
+			Scope: instrumentation.Scope{Name: config.Scope},
+		},
+		sdkmetric.Stream{
+			Name:            config.Rename,
+			Description:     config.Description,
+			Aggregation:     agg,
+			AttributeFilter: getMetricViewAttributeFilter(config),
+		},
+	), nil
+}
+
+func getMetricViewAggregation(
+	config metricViewConfig,
+) (
+	sdkmetric.Aggregation,
+	error,
+) {
+	if config.Drop {
+		return sdkmetric.AggregationDrop{}, nil
+	}
+	if len(config.Boundaries) > 0 && config.ExponentialBuckets != nil {
+		return nil, errors.New("boundaries are either explicit or exponential")
+	}
+	if config.ExponentialBuckets != nil {
+		return getExponentialHistogramAggregation(config.ExponentialBuckets, config.NoMinMax)
+	}
+
+	// Keep the aggregation of the instrument
+	if len(config.Boundaries) == 0 {
+		if config.NoMinMax {
+			return nil, errors.New("noMinMax requires bucket boundaries")
+		}
+		return nil, nil
+	}
+
+	for i := 1; i < len(config.Boundaries); i++ {
+		if config.Boundaries[i] <= config.Boundaries[i-1] {
+			return nil, errors.New("boundaries are not monotonically increasing")
+		}
+	}
+	return sdkmetric.AggregationExplicitBucketHistogram{
+		Boundaries: config.Boundaries,
+		NoMinMax:   config.NoMinMax,
+	}, nil
+}
+
+func getExponentialHistogramAggregation(
+	config *exponentialBucketsConfig,
+	noMinMax bool,
+) (
+	sdkmetric.Aggregation,
+	error,
+) {
+	agg := sdkmetric.AggregationBase2ExponentialHistogram{
+		MaxSize:  defaultExponentialHistogramMaxSize,
+		MaxScale: defaultExponentialHistogramMaxScale,
+		NoMinMax: noMinMax,
+	}
+	if config.MaxSize != 0 {
+		agg.MaxSize = config.MaxSize
+	}
+	if config.MaxScale != nil {
+		agg.MaxScale = *config.MaxScale
+	}
+
+	if agg.MaxSize < 2 || agg.MaxScale < -10 || agg.MaxScale > 20 {
+		return nil, errors.New("exponential buckets require a max size of at least 2 and a max scale between -10 and 20")
+	}
+	return agg, nil
+}
+
+// Returns the filter which keeps either the allowed attributes only or all
+// but the dropped ones. Without any of them, all attributes are kept.
+func getMetricViewAttributeFilter(
+	config metricViewConfig,
+) attribute.Filter {
+	if len(config.AllowAttributes) > 0 {
+		allowed := getAttributeKeySet(config.AllowAttributes)
+		return func(kv attribute.KeyValue) bool {
+			return allowed[kv.Key]
+		}
+	}
+	if len(config.DropAttributes) > 0 {
+		dropped := getAttributeKeySet(config.DropAttributes)
+		return func(kv attribute.KeyValue) bool {
+			return !dropped[kv.Key]
+		}
+	}
+	return nil
+}
+
+func getAttributeKeySet(
+	keys []string,
+) map[attribute.Key]bool {
+	set := map[attribute.Key]bool{}
+	for _, key := range keys {
+		set[attribute.Key(key)] = true
+	}
+	return set
+}